@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/peterargue/flow-info/pkg/checkpoint"
+	"github.com/peterargue/flow-info/pkg/sporks"
+)
+
+func main() {
+	var sporkName, file, stateCommitment string
+
+	flag.StringVar(&sporkName, "spork-name", "", "spork name (e.g. mainnet22, testnet43, etc). defaults to the spork's root checkpoint")
+	flag.StringVar(&file, "file", "", "file or url of the checkpoint header file (e.g. root.checkpoint)")
+	flag.StringVar(&stateCommitment, "state-commitment", "", "expected root hash. defaults to the spork's root state commitment")
+	flag.Parse()
+
+	if sporkName == "" && file == "" {
+		fmt.Println("At least one of --spork-name, --file must be specified")
+		flag.Usage()
+		return
+	}
+
+	if sporkName != "" {
+		sporkInfo, err := sporks.Load()
+		if err != nil {
+			log.Fatalf("error loading sporks: %v", err)
+		}
+
+		spork, err := sporkInfo.Spork(sporkName)
+		if err != nil {
+			log.Fatalf("error loading spork: %v", err)
+		}
+
+		if file == "" {
+			file = spork.StateArtefacts.RootCheckpointFile
+		}
+		if stateCommitment == "" {
+			stateCommitment = spork.RootStateCommitment
+		}
+	}
+
+	c, err := checkpoint.Load(file)
+	if err != nil {
+		log.Fatalf("error loading checkpoint: %v", err)
+	}
+
+	c.Print()
+
+	if stateCommitment == "" {
+		return
+	}
+
+	fmt.Println()
+	err = c.VerifyRootHash(stateCommitment)
+	if err != nil {
+		fmt.Printf("Checkpoint does NOT match: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Checkpoint matches state commitment %s\n", stateCommitment)
+}
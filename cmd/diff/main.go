@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/onflow/flow-go-sdk/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/snapshots"
+	"github.com/peterargue/flow-info/pkg/sporks"
+)
+
+// latestSnapshot is the source name used to load the latest snapshot from an access node.
+const latestSnapshot = "latest"
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "snapshots":
+		diffSnapshots(os.Args[2:])
+	case "identities":
+		diffIdentities(os.Args[2:])
+	default:
+		usage()
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Println("Usage: diff <command> [flags]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  snapshots   show the changes between two protocol state snapshots")
+	fmt.Println("  identities  show the changes between two identity lists")
+}
+
+func diffSnapshots(args []string) {
+	var from, to, accessNode, format string
+
+	flags := flag.NewFlagSet("snapshots", flag.ExitOnError)
+	flags.StringVar(&from, "from", "", "spork name, file or url of the old snapshot")
+	flags.StringVar(&to, "to", "", "spork name, file or url of the new snapshot, or \"latest\" to use the latest snapshot from --access-node")
+	flags.StringVar(&accessNode, "access-node", "", "access node address used to load the \"latest\" snapshot")
+	flags.StringVar(&format, "format", "text", "output format (text or json)")
+	_ = flags.Parse(args)
+
+	if from == "" || to == "" {
+		fmt.Println("Missing --from or --to")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if format != "text" && format != "json" {
+		fmt.Printf("Invalid --format %s\n", format)
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	l := &loader{accessNode: accessNode}
+	load := func(source string) *snapshots.Snapshot {
+		snapshot, err := l.snapshot(source)
+		if err != nil {
+			log.Fatalf("error loading snapshot %s: %v", source, err)
+		}
+		return snapshot
+	}
+
+	diff := snapshots.Diff(load(from), load(to))
+
+	if format == "json" {
+		printJSON(diff)
+		return
+	}
+
+	diff.Print()
+}
+
+func diffIdentities(args []string) {
+	var from, to, source, accessNode, format string
+
+	flags := flag.NewFlagSet("identities", flag.ExitOnError)
+	flags.StringVar(&from, "from", "", "spork name, file or url of the old identities")
+	flags.StringVar(&to, "to", "", "spork name, file or url of the new identities, or \"latest\" to use the latest snapshot from --access-node")
+	flags.StringVar(&source, "source", "node-info", "where identities are read from: node-info (node-infos.pub.json) or snapshot (current epoch participants)")
+	flags.StringVar(&accessNode, "access-node", "", "access node address used to load the \"latest\" snapshot")
+	flags.StringVar(&format, "format", "text", "output format (text or json)")
+	_ = flags.Parse(args)
+
+	if from == "" || to == "" {
+		fmt.Println("Missing --from or --to")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if source != "node-info" && source != "snapshot" {
+		fmt.Printf("Invalid --source %s\n", source)
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if format != "text" && format != "json" {
+		fmt.Printf("Invalid --format %s\n", format)
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	l := &loader{accessNode: accessNode}
+	load := func(name string) identities.IdentityList {
+		var list identities.IdentityList
+		var err error
+		if source == "snapshot" || name == latestSnapshot {
+			list, err = l.snapshotIdentities(name)
+		} else {
+			list, err = l.NodeInfo(name, &identities.Codec{})
+		}
+		if err != nil {
+			log.Fatalf("error loading identities %s: %v", name, err)
+		}
+		return list
+	}
+
+	changes := identities.Diff(load(from), load(to))
+
+	if format == "json" {
+		printJSON(changes)
+		return
+	}
+
+	changes.Print()
+}
+
+func printJSON(value interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(value)
+	if err != nil {
+		log.Fatalf("error encoding diff: %v", err)
+	}
+}
+
+// loader loads snapshots and identities from the supported sources.
+type loader struct {
+	sporks.Loader
+	accessNode string
+}
+
+// snapshot loads a snapshot from a spork name, file or url, or the latest snapshot from the
+// access node.
+func (l *loader) snapshot(source string) (*snapshots.Snapshot, error) {
+	if source == latestSnapshot {
+		if l.accessNode == "" {
+			return nil, fmt.Errorf("--access-node is required to load the latest snapshot")
+		}
+
+		accessClient, err := client.New(l.accessNode,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(20*1024*1024)),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error creating access node client: %w", err)
+		}
+		defer accessClient.Close()
+
+		return snapshots.LoadLatestFromAN(context.Background(), accessClient)
+	}
+
+	return l.Loader.Snapshot(source)
+}
+
+// snapshotIdentities loads the current epoch participants from a snapshot.
+func (l *loader) snapshotIdentities(source string) (identities.IdentityList, error) {
+	snapshot, err := l.snapshot(source)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.CurrentEpochSetup().Identities(), nil
+}
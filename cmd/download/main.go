@@ -4,23 +4,58 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"sync"
+	"time"
 
+	"github.com/peterargue/flow-info/pkg/archive"
 	"github.com/peterargue/flow-info/pkg/info"
 	"github.com/peterargue/flow-info/pkg/sporks"
 )
 
+const defaultWorkers = 4
+
+// artefact is a spork artefact selected for download.
+type artefact struct {
+	name     string
+	url      string
+	checksum string
+	path     string
+
+	// extract is true if the artefact is an archive that is extracted into path
+	extract bool
+}
+
+// result is the outcome of downloading an artefact.
+type result struct {
+	artefact
+	size     int64
+	duration time.Duration
+	err      error
+}
+
 func main() {
 	var sporkName,
 		rootCheckpointFile,
 		rootProtocolStateSnapshot,
 		rootProtocolStateSnapshotSignature,
-		nodeInfo string
+		nodeInfo,
+		protocolDBArchive,
+		protocolDBDir,
+		executionStateArchive,
+		executionStateDir string
+	var workers int
 
 	flag.StringVar(&sporkName, "spork-name", "", "spork name (e.g. mainnet22, testnet43, etc)")
 	flag.StringVar(&rootCheckpointFile, "root-checkpoint", "", "path where rootCheckpointFile will be written")
 	flag.StringVar(&rootProtocolStateSnapshot, "root-protocol-state-snapshot", "", "path where rootProtocolStateSnapshot will be written")
 	flag.StringVar(&rootProtocolStateSnapshotSignature, "root-protocol-state-snapshot-sig", "", "path where rootProtocolStateSnapshotSignature will be written")
 	flag.StringVar(&nodeInfo, "node-info", "", "path where nodeInfo will be written")
+	flag.StringVar(&protocolDBArchive, "protocol-db-archive", "", "path where protocolDBArchive will be written")
+	flag.StringVar(&executionStateArchive, "execution-state-archive", "", "path where executionStateArchive will be written")
+	flag.StringVar(&protocolDBDir, "protocol-db-dir", "", "directory where protocolDBArchive will be extracted")
+	flag.StringVar(&executionStateDir, "execution-state-dir", "", "directory where executionStateArchive will be extracted")
+	flag.IntVar(&workers, "workers", defaultWorkers, "maximum number of files downloaded at once")
 	flag.Parse()
 
 	if sporkName == "" {
@@ -29,8 +64,8 @@ func main() {
 		return
 	}
 
-	if rootCheckpointFile == "" && rootProtocolStateSnapshot == "" && rootProtocolStateSnapshotSignature == "" && nodeInfo == "" {
-		fmt.Println("At least one of --root-checkpoint, --root-protocol-state-snapshot, --root-protocol-state-snapshot-sig, --node-info must be specified")
+	if workers < 1 {
+		fmt.Println("--workers must be at least 1")
 		flag.Usage()
 		return
 	}
@@ -45,35 +80,108 @@ func main() {
 		log.Fatalf("error loading spork: %v", err)
 	}
 
-	if rootCheckpointFile != "" {
-		err = info.Save(spork.StateArtefacts.RootCheckpointFile, rootCheckpointFile)
-		if err != nil {
-			log.Fatalf("error downloading root-checkpoint: %v", err)
+	a := spork.StateArtefacts
+	var selected []artefact
+	for _, candidate := range []artefact{
+		{name: "root-checkpoint", url: a.RootCheckpointFile, path: rootCheckpointFile},
+		{name: "root-protocol-state-snapshot", url: a.RootProtocolStateSnapshot, path: rootProtocolStateSnapshot},
+		{name: "root-protocol-state-snapshot-sig", url: a.RootProtocolStateSnapshotSignature, path: rootProtocolStateSnapshotSignature},
+		{name: "node-info", url: a.NodeInfo, path: nodeInfo},
+		{name: "protocol-db-archive", url: a.ProtocolDBArchive, checksum: a.ProtocolDBArchiveChecksum, path: protocolDBArchive},
+		{name: "execution-state-archive", url: a.ExecutionStateArchive, checksum: a.ExecutionStateArchiveChecksum, path: executionStateArchive},
+		{name: "protocol-db", url: a.ProtocolDBArchive, checksum: a.ProtocolDBArchiveChecksum, path: protocolDBDir, extract: true},
+		{name: "execution-state", url: a.ExecutionStateArchive, checksum: a.ExecutionStateArchiveChecksum, path: executionStateDir, extract: true},
+	} {
+		if candidate.path != "" {
+			selected = append(selected, candidate)
 		}
-		log.Printf("wrote root-checkpoint to %s", rootCheckpointFile)
 	}
 
-	if rootProtocolStateSnapshot != "" {
-		err = info.Save(spork.StateArtefacts.RootProtocolStateSnapshot, rootProtocolStateSnapshot)
-		if err != nil {
-			log.Fatalf("error downloading root-protocol-state-snapshot: %v", err)
-		}
-		log.Printf("wrote root-protocol-state-snapshot to %s", rootProtocolStateSnapshot)
+	if len(selected) == 0 {
+		fmt.Println("At least one of --root-checkpoint, --root-protocol-state-snapshot, --root-protocol-state-snapshot-sig, --node-info, --protocol-db-archive, --execution-state-archive, --protocol-db-dir, --execution-state-dir must be specified")
+		flag.Usage()
+		return
 	}
 
-	if rootProtocolStateSnapshotSignature != "" {
-		err = info.Save(spork.StateArtefacts.RootProtocolStateSnapshotSignature, rootProtocolStateSnapshotSignature)
-		if err != nil {
-			log.Fatalf("error downloading root-protocol-state-snapshot-sig: %v", err)
-		}
-		log.Printf("wrote root-protocol-state-snapshot-sig to %s", rootProtocolStateSnapshotSignature)
+	results := download(selected, workers)
+
+	failed := printSummary(results)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// download downloads the artefacts with at most workers downloads running at once, and returns
+// the results in the same order as the artefacts.
+func download(artefacts []artefact, workers int) []result {
+	results := make([]result, len(artefacts))
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for i, a := range artefacts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = downloadArtefact(a)
+		}()
 	}
+	wg.Wait()
 
-	if nodeInfo != "" {
-		err = info.Save(spork.StateArtefacts.NodeInfo, nodeInfo)
-		if err != nil {
-			log.Fatalf("error downloading node-info: %v", err)
+	return results
+}
+
+func downloadArtefact(a artefact) result {
+	r := result{artefact: a}
+
+	if a.url == "" {
+		r.err = fmt.Errorf("spork does not have a %s", a.name)
+		log.Printf("%s: failed: %v", a.name, r.err)
+		return r
+	}
+
+	log.Printf("%s: downloading %s", a.name, a.url)
+
+	start := time.Now()
+	if a.extract {
+		r.err = archive.ExtractURL(a.url, a.checksum, a.path)
+	} else {
+		r.size, r.err = info.SaveArtefact(a.url, a.checksum, a.path)
+	}
+	r.duration = time.Since(start)
+
+	switch {
+	case r.err != nil:
+		log.Printf("%s: failed: %v", a.name, r.err)
+	case a.extract:
+		log.Printf("%s: extracted to %s", a.name, a.path)
+	default:
+		log.Printf("%s: wrote %d bytes to %s", a.name, r.size, a.path)
+	}
+
+	return r
+}
+
+// printSummary prints the status of each download, and returns the number of failed downloads.
+func printSummary(results []result) int {
+	failed := 0
+
+	fmt.Println()
+	fmt.Println("Summary:")
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+			fmt.Printf("  FAILED  %-34s %v\n", r.name, r.err)
+			continue
+		}
+		if r.extract {
+			fmt.Printf("  OK      %-34s extracted to %s in %s\n", r.name, r.path, r.duration.Round(time.Millisecond))
+			continue
 		}
-		log.Printf("wrote node-info to %s", nodeInfo)
+		fmt.Printf("  OK      %-34s %s (%d bytes in %s)\n", r.name, r.path, r.size, r.duration.Round(time.Millisecond))
 	}
+
+	fmt.Printf("%d of %d downloads succeeded\n", len(results)-failed, len(results))
+	return failed
 }
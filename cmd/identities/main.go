@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/probe"
+	"github.com/peterargue/flow-info/pkg/sporks"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "convert":
+		convert(os.Args[2:])
+	case "probe":
+		probeNodes(os.Args[2:])
+	default:
+		usage()
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Println("Usage: identities <command> [flags]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  convert  convert node infos between json, csv, yaml and markdown")
+	fmt.Println("  probe    check the network connectivity of nodes")
+}
+
+func convert(args []string) {
+	var input, output, format, stakeField string
+
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	flags.StringVar(&input, "input", "", "spork name, or file or url of the node infos (json, csv or yaml, chosen by extension)")
+	flags.StringVar(&output, "output", "", "file to write the node infos to. defaults to stdout")
+	flags.StringVar(&format, "format", "", "output format (json, csv, yaml or markdown). defaults to the --output extension, or json")
+	flags.StringVar(&stakeField, "stake-field", "", "field name stakes are written under (Stake or Weight). defaults to the name used by the input file, or Stake")
+	_ = flags.Parse(args)
+
+	if input == "" {
+		fmt.Println("Missing --input")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	outputFormat := identities.FormatFromPath(output)
+	if format != "" {
+		var err error
+		outputFormat, err = identities.ParseFormat(format)
+		if err != nil {
+			fmt.Printf("Invalid --format %s\n", format)
+			flags.Usage()
+			os.Exit(1)
+		}
+	}
+
+	var codec identities.Codec
+	nodeInfo, err := (&sporks.Loader{}).NodeInfo(input, &codec)
+	if err != nil {
+		log.Fatalf("error loading node infos %s: %v", input, err)
+	}
+
+	codec.Format = outputFormat
+	if stakeField != "" {
+		codec.StakeField, err = identities.ParseStakeField(stakeField)
+		if err != nil {
+			fmt.Printf("Invalid --stake-field %s\n", stakeField)
+			flags.Usage()
+			os.Exit(1)
+		}
+	}
+
+	out := os.Stdout
+	if output != "" {
+		out, err = os.Create(output)
+		if err != nil {
+			log.Fatalf("error creating %s: %v", output, err)
+		}
+		defer out.Close()
+	}
+
+	err = codec.Write(out, nodeInfo)
+	if err != nil {
+		log.Fatalf("error writing node infos: %v", err)
+	}
+}
+
+func probeNodes(args []string) {
+	var input, source, role string
+	var handshake bool
+
+	prober := probe.NewProber()
+
+	flags := flag.NewFlagSet("probe", flag.ExitOnError)
+	flags.StringVar(&input, "input", "", "spork name, or file or url of the node infos or snapshot")
+	flags.StringVar(&source, "source", "node-info", "where identities are read from: node-info (node-infos.pub.json) or snapshot (current epoch participants)")
+	flags.StringVar(&role, "role", "", "only probe nodes with this role")
+	flags.IntVar(&prober.Concurrency, "concurrency", probe.DefaultConcurrency, "maximum number of nodes probed at once")
+	flags.DurationVar(&prober.Timeout, "timeout", probe.DefaultTimeout, "timeout for each node")
+	flags.BoolVar(&handshake, "handshake", false, "run a libp2p handshake to check each node's network key")
+	_ = flags.Parse(args)
+
+	if input == "" {
+		fmt.Println("Missing --input")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if source != "node-info" && source != "snapshot" {
+		fmt.Printf("Invalid --source %s\n", source)
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	var list identities.IdentityList
+	var err error
+	if source == "snapshot" {
+		list, err = loadSnapshotIdentities(input)
+	} else {
+		list, err = (&sporks.Loader{}).NodeInfo(input, &identities.Codec{})
+	}
+	if err != nil {
+		log.Fatalf("error loading identities %s: %v", input, err)
+	}
+
+	if role != "" {
+		r, err := identities.ParseRole(role)
+		if err != nil {
+			fmt.Printf("Invalid --role %s\n", role)
+			flags.Usage()
+			os.Exit(1)
+		}
+		list = list.ByRole(r)
+	}
+
+	prober.Handshake = handshake
+	results := prober.Probe(context.Background(), list)
+	results.Print()
+
+	if len(results.Failed()) > 0 {
+		os.Exit(1)
+	}
+}
+
+// loadSnapshotIdentities loads the current epoch participants from a snapshot file or url, or from
+// a spork's root snapshot.
+func loadSnapshotIdentities(source string) (identities.IdentityList, error) {
+	snapshot, err := (&sporks.Loader{}).Snapshot(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot.CurrentEpochSetup().Identities(), nil
+}
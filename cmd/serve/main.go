@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/peterargue/flow-info/pkg/server"
+)
+
+// accessNodes is a repeatable flag of network=address pairs.
+type accessNodes map[string]string
+
+func (a accessNodes) String() string {
+	var pairs []string
+	for network, address := range a {
+		pairs = append(pairs, network+"="+address)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (a accessNodes) Set(value string) error {
+	network, address, ok := strings.Cut(value, "=")
+	if !ok || network == "" || address == "" {
+		return fmt.Errorf("expected network=address, got %s", value)
+	}
+	a[network] = address
+	return nil
+}
+
+func main() {
+	var listen string
+	var refreshInterval, snapshotTTL time.Duration
+	nodes := accessNodes{}
+
+	flag.StringVar(&listen, "listen", ":8080", "address to listen on")
+	flag.DurationVar(&refreshInterval, "refresh-interval", server.DefaultRefreshInterval, "how often sporks.json is reloaded")
+	flag.DurationVar(&snapshotTTL, "snapshot-ttl", server.DefaultSnapshotTTL, "how long the latest epoch of each network is cached")
+	flag.Var(nodes, "access-node", "access node used for a network's latest snapshot, as network=address (can be repeated). Defaults to the live spork's access node")
+	flag.Parse()
+
+	if refreshInterval <= 0 {
+		fmt.Println("--refresh-interval must be positive")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	s := server.New()
+	s.RefreshInterval = refreshInterval
+	s.SnapshotTTL = snapshotTTL
+	s.AccessNodes = nodes
+
+	err := s.Refresh()
+	if err != nil {
+		log.Fatalf("error loading spork info: %v", err)
+	}
+
+	go s.Run(context.Background())
+
+	log.Printf("listening on %s", listen)
+	err = http.ListenAndServe(listen, s.Handler())
+	if err != nil {
+		log.Fatalf("error serving: %v", err)
+	}
+}
@@ -33,12 +33,12 @@ func main() {
 	}
 
 	fmt.Printf("Current Identities:\n")
-	state := snapshot.SealingSegment.ProtocolStateEntry()
-	for _, identity := range state.EpochEntry.CurrentEpochIdentityTable {
+	for _, identity := range snapshot.CurrentParticipants() {
 		fmt.Printf("NodeID: %s\n", identity.NodeID)
 		fmt.Printf("  Address: %s\n", identity.Address)
 		fmt.Printf("  Role: %s\n", identity.Role)
-		fmt.Printf("  Stake: %d\n", identity.Stake)
+		fmt.Printf("  Status: %s\n", identity.Status)
+		fmt.Printf("  Weight: %d\n", identity.Weight)
 		fmt.Printf("  NetworkPubKey: %s\n", identity.NetworkPubKey)
 		fmt.Printf("  StakingPubKey: %s\n", identity.StakingPubKey)
 	}
@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestBase58Encode(t *testing.T) {
+	// vectors from the base58 encoding draft (draft-msporny-base58)
+	tests := []struct {
+		data string
+		want string
+	}{
+		{data: "", want: ""},
+		{data: "00", want: "1"},
+		{data: "0000287fb4cd", want: "11233QC4"},
+		{data: hex.EncodeToString([]byte("Hello World!")), want: "2NEpo7TZRRrLZSi2U"},
+		{
+			data: hex.EncodeToString([]byte("The quick brown fox jumps over the lazy dog.")),
+			want: "USm3fpXnKG5EUBx2ndxBDMPVciP5hGey2Jh4NDv6gmeo1LkMeiKrLJUUBk6Z",
+		},
+		{data: "39", want: "z"},
+		{data: "3a", want: "21"},
+	}
+
+	for _, test := range tests {
+		data, err := hex.DecodeString(test.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Base58Encode(data); got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.data, test.want, got)
+		}
+	}
+}
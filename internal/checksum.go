@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// ResolveChecksum returns the checksum. If the checksum is the url of a checksum file, the file is
+// downloaded and its content returned.
+func ResolveChecksum(checksum string) (string, error) {
+	if !strings.HasPrefix(checksum, "https://") && !strings.HasPrefix(checksum, "http://") {
+		return checksum, nil
+	}
+
+	data, err := Download(checksum)
+	if err != nil {
+		return "", fmt.Errorf("error downloading checksum: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// ParseChecksum parses a hex encoded sha256 checksum, which may be prefixed by "sha256:" or followed
+// by a file name as written by sha256sum.
+func ParseChecksum(checksum string) ([]byte, error) {
+	fields := strings.Fields(checksum)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid checksum: %q", checksum)
+	}
+
+	sum, err := hex.DecodeString(strings.TrimPrefix(fields[0], "sha256:"))
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("invalid sha256 checksum: %q", checksum)
+	}
+	return sum, nil
+}
+
+// VerifyChecksum checks that the sum of the hashed data matches the expected checksum.
+func VerifyChecksum(hasher hash.Hash, expected []byte) error {
+	if sum := hasher.Sum(nil); !bytes.Equal(sum, expected) {
+		return fmt.Errorf("checksum mismatch: expected %x, got %x", expected, sum)
+	}
+	return nil
+}
@@ -6,11 +6,19 @@ import (
 	"net/http"
 	"os"
 	"time"
+
+	"github.com/peterargue/flow-info/pkg/cache"
 )
 
 const downloadTimeout = time.Second * 120
 
+// Download returns the data at the url. If the default cache is enabled, the data is treated as
+// mutable and reused until the cache's TTL expires.
 func Download(url string) ([]byte, error) {
+	if c := cache.Default(); c != nil {
+		return c.Get(url)
+	}
+
 	client := http.Client{
 		Timeout: downloadTimeout,
 	}
@@ -30,6 +38,10 @@ func Download(url string) ([]byte, error) {
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting data (url=%s): unexpected status %s", url, res.Status)
+	}
+
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
 		return nil, fmt.Errorf("error reading data: %w", err)
@@ -38,6 +50,75 @@ func Download(url string) ([]byte, error) {
 	return body, nil
 }
 
+// OpenImmutable opens a stream to the data at a url whose content never changes, like a spork
+// artefact. Artefacts can be very large, so unlike OpenURL only the wait for the response is
+// limited by a timeout. If the default cache is enabled, the data is cached permanently once it
+// matches the sha256 checksum. Without the cache the checksum is not verified, so callers must
+// verify the data they read. The caller must close the returned reader.
+func OpenImmutable(url string, checksum []byte) (io.ReadCloser, error) {
+	if c := cache.Default(); c != nil {
+		return c.OpenImmutable(url, checksum)
+	}
+	return OpenStream(url)
+}
+
+// OpenStream opens a stream to the data at the url without using the cache. Only the wait for the
+// response is limited by a timeout, so the stream can be used for very large files. The caller
+// must close the returned reader.
+func OpenStream(url string) (io.ReadCloser, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = downloadTimeout
+
+	client := http.Client{
+		Transport: transport,
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error getting data (url=%s): %w", url, err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("error getting data (url=%s): unexpected status %s", url, res.Status)
+	}
+
+	return res.Body, nil
+}
+
+// OpenURL opens a stream to the data at the url. The caller must close the returned reader.
+func OpenURL(url string) (io.ReadCloser, error) {
+	if c := cache.Default(); c != nil {
+		return c.Open(url)
+	}
+
+	client := http.Client{
+		Timeout: downloadTimeout,
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error getting data (url=%s): %w", url, err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("error getting data (url=%s): unexpected status %s", url, res.Status)
+	}
+
+	return res.Body, nil
+}
+
 func ReadFile(path string) ([]byte, error) {
 	file, err := os.Open(path)
 	if err != nil {
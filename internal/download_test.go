@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDownloadStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/snapshot.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Params":{}}`))
+	})
+	mux.HandleFunc("/error.json", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"internal"}`, http.StatusInternalServerError)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	open := map[string]func(url string) (io.ReadCloser, error){
+		"OpenURL":    OpenURL,
+		"OpenStream": OpenStream,
+	}
+	for name, open := range open {
+		t.Run(name, func(t *testing.T) {
+			r, err := open(ts.URL + "/snapshot.json")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			data, err := io.ReadAll(r)
+			r.Close()
+			if err != nil || string(data) != `{"Params":{}}` {
+				t.Errorf("unexpected data %q (%v)", data, err)
+			}
+
+			for _, path := range []string{"/missing.json", "/error.json"} {
+				_, err := open(ts.URL + path)
+				if err == nil || !strings.Contains(err.Error(), "unexpected status") {
+					t.Errorf("%s: expected an unexpected status error, got %v", path, err)
+				}
+			}
+		})
+	}
+
+	for _, path := range []string{"/missing.json", "/error.json"} {
+		_, err := Download(ts.URL + path)
+		if err == nil || !strings.Contains(err.Error(), "unexpected status") {
+			t.Errorf("Download %s: expected an unexpected status error, got %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// JSONObject is a json object that retains the order of its keys.
+type JSONObject struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+// ParseJSON parses json into a tree of *JSONObject, []interface{}, json.Number, string, bool and nil
+// values. Unlike json.Unmarshal, object key order and number formatting are preserved.
+func ParseJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	value, err := parseJSONValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after json value")
+	}
+
+	return value, nil
+}
+
+func parseJSONValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		object := &JSONObject{Values: make(map[string]interface{})}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyToken.(string)
+
+			value, err := parseJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, exists := object.Values[key]; !exists {
+				object.Keys = append(object.Keys, key)
+			}
+			object.Values[key] = value
+		}
+		_, err = decoder.Token()
+		return object, err
+
+	case '[':
+		array := make([]interface{}, 0)
+		for decoder.More() {
+			value, err := parseJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token()
+		return array, err
+	}
+
+	return nil, fmt.Errorf("unexpected delimiter %s", delim)
+}
+
+// JSONFormat is the layout of a json document.
+type JSONFormat struct {
+	// Indent is the indentation of each nesting level, or empty for compact json.
+	Indent string
+	// TrailingNewline is set if the document ends with a newline.
+	TrailingNewline bool
+}
+
+// DetectJSONFormat returns the layout of the json document. Documents written with
+// json.MarshalIndent or json.Marshal are reproduced exactly by MarshalJSONFormat.
+func DetectJSONFormat(data []byte) JSONFormat {
+	format := JSONFormat{
+		TrailingNewline: bytes.HasSuffix(data, []byte("\n")),
+	}
+
+	// the indent is the whitespace before the first nested value
+	trimmed := bytes.TrimSpace(data)
+	start := bytes.IndexByte(trimmed, '\n')
+	if start < 0 {
+		return format
+	}
+	line := trimmed[start+1:]
+	format.Indent = string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+
+	return format
+}
+
+// MarshalJSONFormat encodes a tree returned by ParseJSON using the layout.
+func MarshalJSONFormat(value interface{}, format JSONFormat) ([]byte, error) {
+	data, err := MarshalJSON(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if format.Indent != "" {
+		var buf bytes.Buffer
+		err = json.Indent(&buf, data, "", format.Indent)
+		if err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	}
+
+	if format.TrailingNewline {
+		data = append(data, '\n')
+	}
+
+	return data, nil
+}
+
+// MarshalJSON encodes a tree returned by ParseJSON as compact json.
+func MarshalJSON(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := writeJSONValue(&buf, value)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJSONValue(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case *JSONObject:
+		buf.WriteByte('{')
+		for i, key := range v.Keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			encodedKey, err := json.Marshal(key)
+			if err != nil {
+				return err
+			}
+			buf.Write(encodedKey)
+			buf.WriteByte(':')
+			err = writeJSONValue(buf, v.Values[key])
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, element := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			err := writeJSONValue(buf, element)
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+
+	case json.Number:
+		buf.WriteString(v.String())
+
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	}
+
+	return nil
+}
+
+// MergeJSON applies the values from updated onto original, and returns the merged tree. base is
+// original decoded into the typed model and encoded again, so it holds the fields the model knows.
+// Fields that are only present in original are kept if the model does not know them, and dropped
+// otherwise, since they were deleted from the model. Fields that are only present in updated are
+// added unless they hold a zero value. Values that are equal in both trees keep their original
+// encoding. Array elements are matched by their NodeID or ID field when every element has one,
+// so inserting or removing an element does not move fields onto its neighbours, and by index
+// otherwise.
+func MergeJSON(original, base, updated interface{}) interface{} {
+	switch orig := original.(type) {
+	case *JSONObject:
+		upd, ok := updated.(*JSONObject)
+		if !ok {
+			break
+		}
+		known, _ := base.(*JSONObject)
+
+		merged := &JSONObject{Values: make(map[string]interface{}, len(orig.Values))}
+		for _, key := range orig.Keys {
+			value, ok := upd.Values[key]
+			if !ok {
+				if known != nil {
+					if _, ok := known.Values[key]; ok {
+						continue
+					}
+				}
+				merged.Keys = append(merged.Keys, key)
+				merged.Values[key] = orig.Values[key]
+				continue
+			}
+
+			var baseValue interface{}
+			if known != nil {
+				baseValue = known.Values[key]
+			}
+			merged.Keys = append(merged.Keys, key)
+			merged.Values[key] = MergeJSON(orig.Values[key], baseValue, value)
+		}
+		for _, key := range upd.Keys {
+			if _, ok := orig.Values[key]; ok || isZeroJSON(upd.Values[key]) {
+				continue
+			}
+			merged.Keys = append(merged.Keys, key)
+			merged.Values[key] = upd.Values[key]
+		}
+		return merged
+
+	case []interface{}:
+		upd, ok := updated.([]interface{})
+		if !ok {
+			break
+		}
+
+		if merged, ok := mergeJSONByID(orig, base, upd); ok {
+			return merged
+		}
+
+		merged := make([]interface{}, len(upd))
+		for i := range upd {
+			if i < len(orig) {
+				merged[i] = MergeJSON(orig[i], baseElement(orig, base, i), upd[i])
+			} else {
+				merged[i] = upd[i]
+			}
+		}
+		return merged
+
+	case json.Number:
+		if upd, ok := updated.(json.Number); ok && equalJSONNumbers(orig, upd) {
+			return original
+		}
+
+	case nil:
+		if isZeroJSON(updated) {
+			return original
+		}
+	}
+
+	return updated
+}
+
+// baseElement returns the element of base that corresponds to original[i]. Elements are matched
+// by index, and nil is returned if the arrays differ in length.
+func baseElement(original []interface{}, base interface{}, i int) interface{} {
+	elements, ok := base.([]interface{})
+	if !ok || len(elements) != len(original) {
+		return nil
+	}
+	return elements[i]
+}
+
+// idKeys are the fields used to match array elements, in order of preference.
+var idKeys = []string{"NodeID", "ID"}
+
+// mergeJSONByID merges arrays whose elements are all objects with the same ID field. It returns
+// false if the elements cannot be matched by ID.
+func mergeJSONByID(original []interface{}, base interface{}, updated []interface{}) ([]interface{}, bool) {
+	if len(original) == 0 || len(updated) == 0 {
+		return nil, false
+	}
+
+	for _, key := range idKeys {
+		originalIDs, ok := jsonElementIDs(original, key)
+		if !ok {
+			continue
+		}
+		updatedIDs, ok := jsonElementIDs(updated, key)
+		if !ok {
+			continue
+		}
+
+		// duplicate IDs are matched in order
+		indexes := make(map[string][]int, len(originalIDs))
+		for i, id := range originalIDs {
+			indexes[id] = append(indexes[id], i)
+		}
+
+		merged := make([]interface{}, len(updated))
+		for i, id := range updatedIDs {
+			if matches := indexes[id]; len(matches) > 0 {
+				merged[i] = MergeJSON(original[matches[0]], baseElement(original, base, matches[0]), updated[i])
+				indexes[id] = matches[1:]
+			} else {
+				merged[i] = updated[i]
+			}
+		}
+		return merged, true
+	}
+
+	return nil, false
+}
+
+// jsonElementIDs returns the string value of the key for each element. It returns false if any
+// element is not an object with a string value for the key.
+func jsonElementIDs(array []interface{}, key string) ([]string, bool) {
+	ids := make([]string, len(array))
+	for i, element := range array {
+		object, ok := element.(*JSONObject)
+		if !ok {
+			return nil, false
+		}
+		id, ok := object.Values[key].(string)
+		if !ok {
+			return nil, false
+		}
+		ids[i] = id
+	}
+	return ids, true
+}
+
+// equalJSONNumbers returns true if the numbers are equal. Numbers that may have been decoded into
+// a float64 and encoded again are compared as float64 values.
+func equalJSONNumbers(original, updated json.Number) bool {
+	if original == updated {
+		return true
+	}
+
+	a, errA := strconv.ParseFloat(original.String(), 64)
+	b, errB := strconv.ParseFloat(updated.String(), 64)
+	if errA != nil || errB != nil || a != b {
+		return false
+	}
+
+	encoded, err := json.Marshal(b)
+	return err == nil && string(encoded) == updated.String()
+}
+
+func isZeroJSON(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case *JSONObject:
+		return len(v.Keys) == 0
+	case []interface{}:
+		return len(v) == 0
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	}
+	return false
+}
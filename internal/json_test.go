@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func mergeJSONStrings(t *testing.T, original, base, updated string) string {
+	t.Helper()
+
+	orig, err := ParseJSON([]byte(original))
+	if err != nil {
+		t.Fatalf("error parsing original: %v", err)
+	}
+	known, err := ParseJSON([]byte(base))
+	if err != nil {
+		t.Fatalf("error parsing base: %v", err)
+	}
+	upd, err := ParseJSON([]byte(updated))
+	if err != nil {
+		t.Fatalf("error parsing updated: %v", err)
+	}
+
+	merged, err := MarshalJSON(MergeJSON(orig, known, upd))
+	if err != nil {
+		t.Fatalf("error marshalling merged json: %v", err)
+	}
+	return string(merged)
+}
+
+func TestMergeJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		base     string
+		updated  string
+		want     string
+	}{
+		{
+			name:     "keeps unmodelled fields",
+			original: `{"A":1,"Extra":"x","B":2}`,
+			base:     `{"A":1,"B":2}`,
+			updated:  `{"A":1,"B":3}`,
+			want:     `{"A":1,"Extra":"x","B":3}`,
+		},
+		{
+			name:     "drops deleted fields",
+			original: `{"A":1,"Extra":"x","B":2}`,
+			base:     `{"A":1,"B":2}`,
+			updated:  `{"A":1}`,
+			want:     `{"A":1,"Extra":"x"}`,
+		},
+		{
+			name:     "drops deleted map entries",
+			original: `{"M":{"a":1,"b":2},"Extra":{"c":3}}`,
+			base:     `{"M":{"a":1,"b":2}}`,
+			updated:  `{"M":{"a":1}}`,
+			want:     `{"M":{"a":1},"Extra":{"c":3}}`,
+		},
+		{
+			name:     "drops deleted fields of elements matched by ID",
+			original: `[{"ID":"a","A":1,"Extra":1},{"ID":"b","A":2,"Extra":2}]`,
+			base:     `[{"ID":"a","A":1},{"ID":"b","A":2}]`,
+			updated:  `[{"ID":"b"},{"ID":"a","A":1}]`,
+			want:     `[{"ID":"b","Extra":2},{"ID":"a","A":1,"Extra":1}]`,
+		},
+		{
+			name:     "keeps number encoding",
+			original: `{"A":1.50}`,
+			base:     `{"A":1.5}`,
+			updated:  `{"A":1.5}`,
+			want:     `{"A":1.50}`,
+		},
+		{
+			name:     "adds non-zero fields",
+			original: `{"A":1}`,
+			base:     `{"A":1}`,
+			updated:  `{"A":1,"B":0,"C":"c"}`,
+			want:     `{"A":1,"C":"c"}`,
+		},
+		{
+			name:     "removed element matched by node ID",
+			original: `[{"NodeID":"a","Extra":1},{"NodeID":"b","Extra":2},{"NodeID":"c","Extra":3}]`,
+			base:     `[{"NodeID":"a"},{"NodeID":"b"},{"NodeID":"c"}]`,
+			updated:  `[{"NodeID":"a"},{"NodeID":"c"}]`,
+			want:     `[{"NodeID":"a","Extra":1},{"NodeID":"c","Extra":3}]`,
+		},
+		{
+			name:     "inserted element matched by ID",
+			original: `[{"ID":"a","Extra":1},{"ID":"c","Extra":3}]`,
+			base:     `[{"ID":"a"},{"ID":"c"}]`,
+			updated:  `[{"ID":"a"},{"ID":"b","New":true},{"ID":"c"}]`,
+			want:     `[{"ID":"a","Extra":1},{"ID":"b","New":true},{"ID":"c","Extra":3}]`,
+		},
+		{
+			name:     "reordered elements matched by node ID",
+			original: `[{"NodeID":"a","Extra":1},{"NodeID":"b","Extra":2}]`,
+			base:     `[{"NodeID":"a"},{"NodeID":"b"}]`,
+			updated:  `[{"NodeID":"b"},{"NodeID":"a"}]`,
+			want:     `[{"NodeID":"b","Extra":2},{"NodeID":"a","Extra":1}]`,
+		},
+		{
+			name:     "duplicate IDs matched in order",
+			original: `[{"ID":"a","Extra":1},{"ID":"a","Extra":2}]`,
+			base:     `[{"ID":"a"},{"ID":"a"}]`,
+			updated:  `[{"ID":"a"},{"ID":"a"}]`,
+			want:     `[{"ID":"a","Extra":1},{"ID":"a","Extra":2}]`,
+		},
+		{
+			name:     "elements without IDs matched by index",
+			original: `[{"A":1,"Extra":1},{"A":2,"Extra":2}]`,
+			base:     `[{"A":1},{"A":2}]`,
+			updated:  `[{"A":3}]`,
+			want:     `[{"A":3,"Extra":1}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := mergeJSONStrings(t, test.original, test.base, test.updated)
+			if got != test.want {
+				t.Errorf("got %s, expected %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMarshalJSONFormat(t *testing.T) {
+	value := map[string]interface{}{
+		"A": []interface{}{1, "two", map[string]interface{}{"B": nil}},
+		"C": map[string]interface{}{},
+		"D": []interface{}{},
+	}
+
+	compact, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indented, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tabs, err := json.MarshalIndent(value, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range [][]byte{compact, indented, tabs, append(indented, '\n')} {
+		tree, err := ParseJSON(data)
+		if err != nil {
+			t.Fatalf("error parsing json: %v", err)
+		}
+
+		encoded, err := MarshalJSONFormat(tree, DetectJSONFormat(data))
+		if err != nil {
+			t.Fatalf("error marshalling json: %v", err)
+		}
+		if string(encoded) != string(data) {
+			t.Errorf("round trip changed json:\n%s\nexpected:\n%s", encoded, data)
+		}
+	}
+}
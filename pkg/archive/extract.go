@@ -0,0 +1,354 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/peterargue/flow-info/internal"
+)
+
+// stagingSuffix is appended to the target directory to get the directory archives are extracted
+// into before being moved into place.
+const stagingSuffix = ".partial"
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ExtractURL streams a tar archive from a url and extracts it into dir. See Extract.
+//
+// The checksum may also be the url of a checksum file, which is downloaded first.
+func ExtractURL(url, checksum, dir string) error {
+	checksum, err := internal.ResolveChecksum(checksum)
+	if err != nil {
+		return err
+	}
+
+	r, err := internal.OpenStream(url)
+	if err != nil {
+		return fmt.Errorf("error downloading archive: %w", err)
+	}
+	defer r.Close()
+
+	return Extract(r, checksum, dir)
+}
+
+// Extract extracts a tar archive into dir. The archive may be uncompressed, or compressed with gzip
+// or zstd, which is detected from the data.
+//
+// If checksum is not empty, it is the hex encoded sha256 of the archive, optionally followed by a
+// file name as written by sha256sum. The checksum is computed while extracting, and the archive is
+// rejected if it does not match.
+//
+// The archive is extracted into a staging directory next to dir, which is only moved to dir once
+// the whole archive was extracted and verified. Staging directories left behind by interrupted
+// runs are removed, so extraction can safely be retried. dir must not exist, or be empty.
+//
+// Entries that would be written outside of dir, including through links, are rejected. Links must
+// resolve to files or directories inside the archive, and hard links must point to regular files.
+func Extract(r io.Reader, checksum, dir string) error {
+	var expected []byte
+	if checksum != "" {
+		var err error
+		expected, err = internal.ParseChecksum(checksum)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := checkTarget(dir)
+	if err != nil {
+		return err
+	}
+
+	staging := filepath.Clean(dir) + stagingSuffix
+	err = os.RemoveAll(staging)
+	if err != nil {
+		return fmt.Errorf("error removing previous staging directory: %w", err)
+	}
+
+	err = os.MkdirAll(staging, 0755)
+	if err != nil {
+		return fmt.Errorf("error creating staging directory: %w", err)
+	}
+
+	err = extract(r, expected, staging)
+	if err != nil {
+		_ = os.RemoveAll(staging)
+		return err
+	}
+
+	// an empty target directory is replaced by the extracted archive
+	_ = os.Remove(dir)
+
+	err = os.Rename(staging, dir)
+	if err != nil {
+		return fmt.Errorf("error moving extracted archive into place: %w", err)
+	}
+
+	return nil
+}
+
+// checkTarget checks that the target directory does not exist, or is empty.
+func checkTarget(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading target directory: %w", err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("target directory %s is not empty", dir)
+	}
+	return nil
+}
+
+func extract(r io.Reader, expected []byte, dir string) error {
+	hasher := sha256.New()
+	raw := io.TeeReader(r, hasher)
+
+	tr, closer, err := decompress(raw)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	links, err := extractTar(tar.NewReader(tr), dir)
+	if err != nil {
+		return err
+	}
+
+	err = checkLinks(dir, links)
+	if err != nil {
+		return err
+	}
+
+	if expected == nil {
+		return nil
+	}
+
+	// the tar reader stops at the end of archive marker, so read any trailing padding to include
+	// it in the checksum
+	_, err = io.Copy(io.Discard, raw)
+	if err != nil {
+		return fmt.Errorf("error reading archive: %w", err)
+	}
+
+	return internal.VerifyChecksum(hasher, expected)
+}
+
+// decompress returns a reader of the uncompressed tar data, detecting the compression from the
+// first bytes of the stream.
+func decompress(r io.Reader) (io.Reader, func(), error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, nil, fmt.Errorf("error reading archive: %w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading gzip archive: %w", err)
+		}
+		return gr, func() { gr.Close() }, nil
+
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading zstd archive: %w", err)
+		}
+		return zr, zr.Close, nil
+	}
+
+	return br, func() {}, nil
+}
+
+// extractTar extracts all entries into dir, and returns the paths of the extracted symlinks.
+func extractTar(tr *tar.Reader, dir string) ([]string, error) {
+	var links []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return links, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading archive: %w", err)
+		}
+
+		err = extractEntry(tr, header, dir)
+		if err != nil {
+			return nil, fmt.Errorf("error extracting %s: %w", header.Name, err)
+		}
+
+		if header.Typeflag == tar.TypeSymlink {
+			links = append(links, header.Name)
+		}
+	}
+}
+
+// checkLinks checks that the symlinks resolve to paths inside dir. Each link target is checked
+// when the link is extracted, but later entries may change what the target path resolves to.
+func checkLinks(dir string, links []string) error {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		resolved, err := filepath.EvalSymlinks(filepath.Join(dir, filepath.FromSlash(link)))
+		if err != nil {
+			return fmt.Errorf("error resolving link %s: %w", link, err)
+		}
+
+		rel, err := filepath.Rel(root, resolved)
+		if err != nil || !filepath.IsLocal(rel) {
+			return fmt.Errorf("link %s resolves outside of the archive", link)
+		}
+	}
+	return nil
+}
+
+func extractEntry(tr *tar.Reader, header *tar.Header, dir string) error {
+	name, err := localPath(header.Name)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return nil
+	}
+
+	err = checkParents(dir, name)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, name)
+	mode := header.FileInfo().Mode().Perm()
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(path, 0755)
+
+	case tar.TypeReg:
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+
+		// replace existing entries instead of writing through them, since they may be links
+		err = os.Remove(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(file, tr)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+
+	case tar.TypeSymlink:
+		// targets are resolved relative to the link's directory, and must stay inside the archive
+		if filepath.IsAbs(header.Linkname) {
+			return fmt.Errorf("link target %s is outside of the archive", header.Linkname)
+		}
+		if _, err := localPath(filepath.Join(filepath.Dir(name), header.Linkname)); err != nil {
+			return fmt.Errorf("link target %s is outside of the archive", header.Linkname)
+		}
+
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(header.Linkname, path)
+
+	case tar.TypeLink:
+		target, err := localPath(header.Linkname)
+		if err != nil {
+			return fmt.Errorf("link target %s is outside of the archive", header.Linkname)
+		}
+		err = checkParents(dir, target)
+		if err != nil {
+			return err
+		}
+
+		// a hard link to a symlink is a copy of the symlink, whose relative target resolves from
+		// the new link's directory, so hard links may only point to regular files
+		info, err := os.Lstat(filepath.Join(dir, target))
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("link target %s is not a regular file", header.Linkname)
+		}
+
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+		return os.Link(filepath.Join(dir, target), path)
+
+	case tar.TypeXGlobalHeader:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported entry type %q", header.Typeflag)
+}
+
+// localPath returns the cleaned, relative path of an archive entry, or an error if the path would
+// be outside of the extraction directory.
+func localPath(name string) (string, error) {
+	name = filepath.Clean(filepath.FromSlash(name))
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("path %s is outside of the archive", name)
+	}
+	return name, nil
+}
+
+// checkParents checks that none of the parent directories of name are symlinks, so entries can't
+// be written outside of dir through a link extracted earlier.
+func checkParents(dir, name string) error {
+	parent := filepath.Dir(name)
+	if parent == "." {
+		return nil
+	}
+
+	path := dir
+	for _, part := range strings.Split(parent, string(filepath.Separator)) {
+		path = filepath.Join(path, part)
+
+		info, err := os.Lstat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("path %s is inside of a link", name)
+		}
+	}
+	return nil
+}
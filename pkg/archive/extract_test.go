@@ -0,0 +1,345 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type entry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+	mode     int64
+}
+
+func file(name, body string) entry {
+	return entry{name: name, typeflag: tar.TypeReg, body: body, mode: 0644}
+}
+
+func dir(name string) entry {
+	return entry{name: name, typeflag: tar.TypeDir, mode: 0755}
+}
+
+func symlink(name, target string) entry {
+	return entry{name: name, typeflag: tar.TypeSymlink, linkname: target, mode: 0777}
+}
+
+func hardlink(name, target string) entry {
+	return entry{name: name, typeflag: tar.TypeLink, linkname: target, mode: 0644}
+}
+
+// makeArchive returns a tar archive of the entries, compressed with gzip, zstd or not at all.
+func makeArchive(t *testing.T, compression string, entries ...entry) []byte {
+	t.Helper()
+
+	var data bytes.Buffer
+	tw := tar.NewWriter(&data)
+	for _, e := range entries {
+		header := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Linkname: e.linkname,
+			Mode:     e.mode,
+			Size:     int64(len(e.body)),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var compressed bytes.Buffer
+	switch compression {
+	case "none":
+		return data.Bytes()
+	case "gzip":
+		w := gzip.NewWriter(&compressed)
+		_, _ = w.Write(data.Bytes())
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	case "zstd":
+		w, err := zstd.NewWriter(&compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write(data.Bytes())
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatalf("unknown compression %s", compression)
+	}
+	return compressed.Bytes()
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// assertNotExtracted checks that neither the target nor the staging directory exist.
+func assertNotExtracted(t *testing.T, target string) {
+	t.Helper()
+
+	for _, path := range []string{target, target + stagingSuffix} {
+		if _, err := os.Lstat(path); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected %s not to exist, got %v", path, err)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	entries := []entry{
+		dir("data/"),
+		file("data/000001.sst", "sst data"),
+		file("data/nested/MANIFEST", "manifest"),
+		symlink("data/CURRENT", "nested/MANIFEST"),
+		hardlink("data/000001.copy", "data/000001.sst"),
+		{name: "run.sh", typeflag: tar.TypeReg, body: "#!/bin/sh", mode: 0755},
+	}
+
+	for _, compression := range []string{"none", "gzip", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			data := makeArchive(t, compression, entries...)
+			target := filepath.Join(t.TempDir(), "protocol-db")
+
+			err := Extract(bytes.NewReader(data), checksum(data), target)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for path, want := range map[string]string{
+				"data/000001.sst":      "sst data",
+				"data/nested/MANIFEST": "manifest",
+				"data/CURRENT":         "manifest",
+				"data/000001.copy":     "sst data",
+				"run.sh":               "#!/bin/sh",
+			} {
+				if got := readFile(t, filepath.Join(target, path)); got != want {
+					t.Errorf("%s: expected %q, got %q", path, want, got)
+				}
+			}
+
+			link, err := os.Readlink(filepath.Join(target, "data/CURRENT"))
+			if err != nil || link != "nested/MANIFEST" {
+				t.Errorf("expected a symlink to nested/MANIFEST, got %q (%v)", link, err)
+			}
+
+			info, err := os.Stat(filepath.Join(target, "run.sh"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm()&0100 == 0 {
+				t.Errorf("expected run.sh to be executable, got %s", info.Mode())
+			}
+
+			if _, err := os.Stat(target + stagingSuffix); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("expected the staging directory to be removed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractChecksumFormats(t *testing.T) {
+	data := makeArchive(t, "gzip", file("a", "a"))
+	sum := checksum(data)
+
+	for _, format := range []string{
+		"",
+		sum,
+		strings.ToUpper(sum),
+		"sha256:" + sum,
+		sum + "  protocol-db.tar.gz\n",
+	} {
+		target := filepath.Join(t.TempDir(), "out")
+		if err := Extract(bytes.NewReader(data), format, target); err != nil {
+			t.Errorf("checksum %q: unexpected error: %v", format, err)
+		}
+	}
+}
+
+func TestExtractChecksumMismatch(t *testing.T) {
+	for _, compression := range []string{"none", "gzip", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			data := makeArchive(t, compression, file("a", "a"), file("b", "b"))
+			target := filepath.Join(t.TempDir(), "out")
+
+			err := Extract(bytes.NewReader(data), strings.Repeat("00", sha256.Size), target)
+			if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+				t.Fatalf("expected a checksum mismatch, got %v", err)
+			}
+			assertNotExtracted(t, target)
+		})
+	}
+}
+
+func TestExtractTrailingData(t *testing.T) {
+	// data after the end of archive marker is included in the checksum
+	data := makeArchive(t, "none", file("a", "a"))
+	sum := checksum(data)
+	data = append(data, make([]byte, 1024)...)
+
+	target := filepath.Join(t.TempDir(), "out")
+	err := Extract(bytes.NewReader(data), sum, target)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	assertNotExtracted(t, target)
+}
+
+func TestExtractInvalidChecksum(t *testing.T) {
+	data := makeArchive(t, "none", file("a", "a"))
+
+	for _, invalid := range []string{"not-hex", "abcd", " "} {
+		target := filepath.Join(t.TempDir(), "out")
+		err := Extract(bytes.NewReader(data), invalid, target)
+		if err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("checksum %q: expected an invalid checksum error, got %v", invalid, err)
+		}
+		assertNotExtracted(t, target)
+	}
+}
+
+func TestExtractRejectsTraversal(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+	}{
+		{name: "parent path", entries: []entry{file("../evil", "x")}},
+		{name: "nested parent path", entries: []entry{file("data/../../evil", "x")}},
+		{name: "absolute path", entries: []entry{file("/tmp/evil", "x")}},
+		{name: "symlink to parent", entries: []entry{symlink("link", "../evil")}},
+		{name: "absolute symlink", entries: []entry{symlink("link", "/etc/passwd")}},
+		{name: "hardlink to parent", entries: []entry{hardlink("link", "../evil")}},
+		{name: "write through symlink", entries: []entry{
+			dir("data/"),
+			symlink("link", "data"),
+			file("link/evil", "x"),
+		}},
+		{name: "hardlink through symlink", entries: []entry{
+			dir("data/"),
+			file("data/file", "x"),
+			symlink("link", "data"),
+			hardlink("copy", "link/file"),
+		}},
+		{name: "hardlink to symlink", entries: []entry{
+			// the symlink resolves to a/x, but its hard link copy resolves next to the target
+			dir("a/b/"),
+			file("a/x", "x"),
+			symlink("a/b/l", "../x"),
+			hardlink("l2", "a/b/l"),
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parent := t.TempDir()
+			target := filepath.Join(parent, "sub", "out")
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			data := makeArchive(t, "gzip", test.entries...)
+			err := Extract(bytes.NewReader(data), "", target)
+			if err == nil {
+				t.Fatal("expected the archive to be rejected")
+			}
+			assertNotExtracted(t, target)
+
+			// nothing was written next to the target
+			if _, err := os.Lstat(filepath.Join(parent, "sub", "evil")); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("expected no file outside of the target, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractTarget(t *testing.T) {
+	data := makeArchive(t, "none", file("a", "a"))
+
+	// an empty target directory is replaced
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.Mkdir(empty, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Extract(bytes.NewReader(data), "", empty); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a non-empty target directory is not overwritten
+	if err := Extract(bytes.NewReader(data), "", empty); err == nil || !strings.Contains(err.Error(), "is not empty") {
+		t.Fatalf("expected a non-empty target error, got %v", err)
+	}
+
+	// staging directories left by an interrupted run are replaced
+	target := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(filepath.Join(target+stagingSuffix, "stale"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Extract(bytes.NewReader(data), "", target); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "stale")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the stale staging directory to be removed, got %v", err)
+	}
+}
+
+func TestExtractURL(t *testing.T) {
+	data := makeArchive(t, "zstd", file("a", "archive"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/archive.tar.zst", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	})
+	mux.HandleFunc("/archive.tar.zst.sha256", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(checksum(data) + "  archive.tar.zst\n"))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	target := filepath.Join(t.TempDir(), "out")
+	err := ExtractURL(ts.URL+"/archive.tar.zst", ts.URL+"/archive.tar.zst.sha256", target)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := readFile(t, filepath.Join(target, "a")); got != "archive" {
+		t.Errorf("expected %q, got %q", "archive", got)
+	}
+
+	target = filepath.Join(t.TempDir(), "out")
+	err = ExtractURL(ts.URL+"/missing.tar.zst", "", target)
+	if err == nil {
+		t.Fatal("expected an error for a missing archive")
+	}
+	assertNotExtracted(t, target)
+}
@@ -0,0 +1,302 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long mutable downloads are used before they are revalidated.
+	DefaultTTL = time.Hour
+
+	// DirEnv is the environment variable that enables the default cache in the given directory.
+	DirEnv = "FLOW_INFO_CACHE_DIR"
+
+	// responseTimeout limits the wait for a response, the same as other downloads. Cached files
+	// can be very large, so the transfer itself is not limited.
+	responseTimeout = 120 * time.Second
+)
+
+// defaultClient is used for downloads when the cache has no client.
+var defaultClient = newDefaultClient()
+
+func newDefaultClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = responseTimeout
+
+	return &http.Client{
+		Transport: transport,
+	}
+}
+
+var defaultCache atomic.Pointer[Cache]
+
+func init() {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		SetDefault(New(dir))
+	}
+}
+
+// Default returns the cache used for downloads, or nil if caching is disabled.
+func Default() *Cache {
+	return defaultCache.Load()
+}
+
+// SetDefault sets the cache used for downloads. Setting nil disables caching.
+func SetDefault(c *Cache) {
+	defaultCache.Store(c)
+}
+
+// Cache is an on-disk cache of downloaded files.
+//
+// Mutable files, like sporks.json, are reused for TTL and then revalidated with the server using
+// their ETag or Last-Modified headers. Immutable files with a sha256 checksum, like spork archives,
+// are verified against the checksum, then downloaded once and kept forever.
+type Cache struct {
+	// Dir is the directory where files are stored.
+	Dir string
+
+	// TTL is how long mutable files are used before they are revalidated.
+	TTL time.Duration
+
+	// Client is the http client used for downloads. If nil, a client that limits the wait for
+	// each response is used.
+	Client *http.Client
+}
+
+// New returns a cache that stores files in dir, using the default TTL.
+func New(dir string) *Cache {
+	return &Cache{
+		Dir: dir,
+		TTL: DefaultTTL,
+	}
+}
+
+// entry is the metadata stored alongside a cached file.
+type entry struct {
+	URL          string
+	Checksum     string `json:",omitempty"`
+	ETag         string `json:",omitempty"`
+	LastModified string `json:",omitempty"`
+	Fetched      time.Time
+}
+
+// Get returns the data at a mutable url, using the cached copy while it is fresh.
+func (c *Cache) Get(url string) ([]byte, error) {
+	path, err := c.fetch(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return readFile(path)
+}
+
+// GetImmutable returns the data at an immutable url. The checksum is the sha256 of the content, and
+// the download fails if it does not match. Artefacts republished under the same url with a new
+// checksum are downloaded again. Without a checksum a republished file could not be detected, so
+// the file is revalidated like a mutable file.
+func (c *Cache) GetImmutable(url string, checksum []byte) ([]byte, error) {
+	path, err := c.fetch(url, checksum)
+	if err != nil {
+		return nil, err
+	}
+	return readFile(path)
+}
+
+// Open is like Get, but returns the cached file instead of reading it into memory. The caller must
+// close the returned file.
+func (c *Cache) Open(url string) (*os.File, error) {
+	path, err := c.fetch(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// OpenImmutable is like GetImmutable, but returns the cached file instead of reading it into
+// memory. The caller must close the returned file.
+func (c *Cache) OpenImmutable(url string, checksum []byte) (*os.File, error) {
+	path, err := c.fetch(url, checksum)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// fetch makes sure the url is cached and fresh, and returns the path of the cached file. Files with
+// a checksum are immutable.
+func (c *Cache) fetch(url string, checksum []byte) (string, error) {
+	immutable := checksum != nil
+	dataPath, entryPath := c.paths(url, checksum)
+
+	cached, err := readEntry(entryPath)
+	if err != nil {
+		return "", err
+	}
+	if cached != nil {
+		if _, err := os.Stat(dataPath); err != nil {
+			cached = nil
+		}
+	}
+
+	if cached != nil && (immutable || time.Since(cached.Fetched) < c.TTL) {
+		return dataPath, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %w", err)
+	}
+
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	client := c.Client
+	if client == nil {
+		client = defaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error getting data (url=%s): %w", url, err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && cached != nil:
+		cached.Fetched = time.Now()
+		return dataPath, c.writeEntry(entryPath, cached)
+
+	case res.StatusCode == http.StatusOK:
+		err = c.store(dataPath, res.Body, checksum)
+		if err != nil {
+			return "", fmt.Errorf("error caching data (url=%s): %w", url, err)
+		}
+
+		return dataPath, c.writeEntry(entryPath, &entry{
+			URL:          url,
+			Checksum:     hex.EncodeToString(checksum),
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+			Fetched:      time.Now(),
+		})
+	}
+
+	return "", fmt.Errorf("error getting data (url=%s): unexpected status %s", url, res.Status)
+}
+
+// paths returns the paths of the data and metadata files for a url.
+func (c *Cache) paths(url string, checksum []byte) (string, string) {
+	key := url
+	if checksum != nil {
+		key = "immutable\x00" + url + "\x00" + hex.EncodeToString(checksum)
+	}
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+
+	return filepath.Join(c.Dir, name+".data"), filepath.Join(c.Dir, name+".json")
+}
+
+// store writes the data to a temporary file and moves it into place, so readers never see a
+// partially written file. If checksum is set, the file is only moved into place if the sha256 of
+// the data matches.
+func (c *Cache) store(path string, r io.Reader, checksum []byte) error {
+	hasher := sha256.New()
+	return c.writeFile(path, "download-*", func(w io.Writer) error {
+		_, err := io.Copy(io.MultiWriter(w, hasher), r)
+		if err != nil {
+			return fmt.Errorf("error downloading data: %w", err)
+		}
+
+		if sum := hasher.Sum(nil); checksum != nil && !bytes.Equal(sum, checksum) {
+			return fmt.Errorf("checksum mismatch: expected %x, got %x", checksum, sum)
+		}
+		return nil
+	})
+}
+
+// writeFile writes a temporary file with write and moves it to path once write succeeds.
+func (c *Cache) writeFile(path, pattern string, write func(w io.Writer) error) error {
+	err := os.MkdirAll(c.Dir, 0755)
+	if err != nil {
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(c.Dir, pattern)
+	if err != nil {
+		return fmt.Errorf("error creating cache file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	err = write(file)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("error writing cache file: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return fmt.Errorf("error writing cache file: %w", err)
+	}
+	return nil
+}
+
+func readEntry(path string) (*entry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading cache entry: %w", err)
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		// treat corrupt entries as missing, so they are downloaded again
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// writeEntry writes the entry to a temporary file and moves it into place, so an interrupted write
+// never leaves a truncated entry.
+func (c *Cache) writeEntry(path string, e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return c.writeFile(path, "entry-*", func(w io.Writer) error {
+		_, err := w.Write(data)
+		if err != nil {
+			return fmt.Errorf("error writing cache entry: %w", err)
+		}
+		return nil
+	})
+}
+
+func readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cache file: %w", err)
+	}
+	return data, nil
+}
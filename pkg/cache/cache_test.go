@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// server serves the current body with an ETag, and counts full and conditional requests.
+type server struct {
+	body        atomic.Value
+	requests    atomic.Int32
+	notModified atomic.Int32
+}
+
+func newServer(t *testing.T, body string) (*server, *httptest.Server) {
+	t.Helper()
+
+	s := &server{}
+	s.body.Store(body)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+
+		body := s.body.Load().(string)
+		etag := `"` + body + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			s.notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+
+	return s, ts
+}
+
+func get(t *testing.T, fetch func() ([]byte, error), want string) {
+	t.Helper()
+
+	data, err := fetch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != want {
+		t.Fatalf("expected %q, got %q", want, data)
+	}
+}
+
+func TestGetRevalidatesAfterTTL(t *testing.T) {
+	s, ts := newServer(t, "v1")
+	c := New(t.TempDir())
+	fetch := func() ([]byte, error) { return c.Get(ts.URL) }
+
+	get(t, fetch, "v1")
+	get(t, fetch, "v1")
+	if n := s.requests.Load(); n != 1 {
+		t.Fatalf("expected the fresh copy to be reused, got %d requests", n)
+	}
+
+	c.TTL = 0
+	get(t, fetch, "v1")
+	if n := s.notModified.Load(); n != 1 {
+		t.Fatalf("expected a conditional request, got %d", n)
+	}
+
+	s.body.Store("v2")
+	get(t, fetch, "v2")
+}
+
+func sum(data string) []byte {
+	s := sha256.Sum256([]byte(data))
+	return s[:]
+}
+
+func TestGetImmutableWithChecksum(t *testing.T) {
+	s, ts := newServer(t, "v1")
+	c := New(t.TempDir())
+	c.TTL = 0
+
+	get(t, func() ([]byte, error) { return c.GetImmutable(ts.URL, sum("v1")) }, "v1")
+
+	// the file is kept for the checksum, even after it is republished
+	s.body.Store("v2")
+	get(t, func() ([]byte, error) { return c.GetImmutable(ts.URL, sum("v1")) }, "v1")
+	if n := s.requests.Load(); n != 1 {
+		t.Fatalf("expected 1 request, got %d", n)
+	}
+
+	// a new checksum downloads the republished file
+	get(t, func() ([]byte, error) { return c.GetImmutable(ts.URL, sum("v2")) }, "v2")
+}
+
+func TestGetImmutableChecksumMismatch(t *testing.T) {
+	s, ts := newServer(t, "corrupt")
+	c := New(t.TempDir())
+
+	_, err := c.GetImmutable(ts.URL, sum("v1"))
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+
+	// nothing is cached for a mismatched download, so the next request downloads the file again
+	s.body.Store("v1")
+	get(t, func() ([]byte, error) { return c.GetImmutable(ts.URL, sum("v1")) }, "v1")
+	if n := s.requests.Load(); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+
+	files, err := os.ReadDir(c.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("expected only the data and entry files, got %d files", len(files))
+	}
+}
+
+func TestGetImmutableWithoutChecksum(t *testing.T) {
+	s, ts := newServer(t, "v1")
+	c := New(t.TempDir())
+	c.TTL = 0
+	fetch := func() ([]byte, error) { return c.GetImmutable(ts.URL, nil) }
+
+	get(t, fetch, "v1")
+
+	s.body.Store("v2")
+	get(t, fetch, "v2")
+}
+
+func TestOpenImmutable(t *testing.T) {
+	_, ts := newServer(t, "v1")
+	c := New(t.TempDir())
+
+	file, err := c.OpenImmutable(ts.URL, sum("v1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+
+	data := make([]byte, 8)
+	n, _ := file.Read(data)
+	if string(data[:n]) != "v1" {
+		t.Fatalf("expected %q, got %q", "v1", data[:n])
+	}
+
+	if _, err := c.OpenImmutable(ts.URL, sum("v2")); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+}
+
+func TestCorruptEntryIsDownloadedAgain(t *testing.T) {
+	s, ts := newServer(t, "v1")
+	c := New(t.TempDir())
+	fetch := func() ([]byte, error) { return c.Get(ts.URL) }
+
+	get(t, fetch, "v1")
+
+	_, entryPath := c.paths(ts.URL, nil)
+	if err := os.WriteFile(entryPath, []byte(`{"URL":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	get(t, fetch, "v1")
+	if n := s.requests.Load(); n != 2 {
+		t.Fatalf("expected the corrupt entry to be downloaded again, got %d requests", n)
+	}
+}
+
+func TestGetUnexpectedStatus(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	c := New(t.TempDir())
+	if _, err := c.Get(ts.URL); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestDefaultClientTimeout(t *testing.T) {
+	transport, ok := defaultClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected an *http.Transport, got %T", defaultClient.Transport)
+	}
+	if transport.ResponseHeaderTimeout != responseTimeout {
+		t.Errorf("expected a response timeout of %s, got %s", responseTimeout, transport.ResponseHeaderTimeout)
+	}
+	if transport == http.DefaultTransport {
+		t.Error("expected the default transport to be left unchanged")
+	}
+}
+
+func TestClientIsUsed(t *testing.T) {
+	// the handler never responds in time, so the request must fail at the client's timeout
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	c := New(t.TempDir())
+	c.Client = &http.Client{Timeout: 100 * time.Millisecond}
+
+	start := time.Now()
+	if _, err := c.Get(ts.URL); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request took %s, expected it to stop at the client timeout", elapsed)
+	}
+}
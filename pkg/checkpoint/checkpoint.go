@@ -0,0 +1,361 @@
+package checkpoint
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+const (
+	magicHeader   uint16 = 0x2137
+	magicSubtrie  uint16 = 0x2136
+	magicTopTries uint16 = 0x2135
+
+	VersionV5 uint16 = 0x05
+	VersionV6 uint16 = 0x06
+
+	// subtrieCount is the number of subtrie files in a V6 checkpoint. The top level tries are stored
+	// in the file after the last subtrie file.
+	subtrieCount = 16
+
+	encodedHeaderSize    = 4  // magic (2) + version (2)
+	encodedChecksumSize  = 4  // crc32
+	encodedNodeCountSize = 8  // uint64
+	encodedTrieCountSize = 2  // uint16
+	encodedTrieSize      = 56 // root node index (8) + register count (8) + register size (8) + root hash (32)
+
+	// v6HeaderFileSize is the size of the V6 header file: the header, subtrie count, a checksum for
+	// each subtrie file and the top tries file, and the header file's checksum.
+	v6HeaderFileSize = encodedHeaderSize + 2 + (subtrieCount+2)*encodedChecksumSize
+)
+
+// crc32Table is the table used by flow-go for checkpoint checksums.
+var crc32Table = crc32.MakeTable(crc32.Castagnoli)
+
+// Checkpoint describes a flow-go execution state checkpoint. It is read from the headers and
+// footers of the checkpoint files, without loading the tries.
+type Checkpoint struct {
+	Version uint16
+
+	// Files is the list of files that make up the checkpoint. V5 checkpoints are a single file,
+	// and V6 checkpoints are a header file, followed by the subtrie files and the top level tries
+	// file.
+	Files []File
+
+	// NodeCount is the total number of trie nodes in the checkpoint.
+	NodeCount uint64
+
+	Tries []Trie
+}
+
+// File is one of the files of a checkpoint.
+type File struct {
+	Name      string
+	Size      int64
+	Checksum  uint32
+	NodeCount uint64
+}
+
+// Trie is a trie stored in a checkpoint.
+type Trie struct {
+	RootIndex     uint64
+	RegisterCount uint64
+	RegisterSize  uint64
+	RootHash      string
+}
+
+// Load reads a checkpoint from a file or url. For V6 checkpoints, the location is the header file,
+// e.g. root.checkpoint, and the other files are expected next to it. Only the headers and footers
+// of the files are read, so loading a checkpoint from a url does not download it.
+func Load(location string) (*Checkpoint, error) {
+	src := newSource(location)
+
+	f, err := src.open(location)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	version, err := readHeader(f, magicHeader)
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint header: %w", err)
+	}
+
+	switch version {
+	case VersionV5:
+		return readV5(f, location)
+	case VersionV6:
+		return readV6(src, f, location)
+	}
+	return nil, fmt.Errorf("unsupported checkpoint version: %d", version)
+}
+
+// RootHash returns the root hash of the last trie, which is the state commitment of the checkpoint.
+func (c *Checkpoint) RootHash() string {
+	if len(c.Tries) == 0 {
+		return ""
+	}
+	return c.Tries[len(c.Tries)-1].RootHash
+}
+
+// VerifyRootHash checks that the checkpoint's root hash matches the state commitment.
+func (c *Checkpoint) VerifyRootHash(stateCommitment string) error {
+	expected := strings.ToLower(strings.TrimPrefix(stateCommitment, "0x"))
+	if rootHash := c.RootHash(); rootHash != expected {
+		return fmt.Errorf("checkpoint root hash %s does not match state commitment %s", rootHash, expected)
+	}
+	return nil
+}
+
+// Print prints the checkpoint details.
+func (c *Checkpoint) Print() {
+	fmt.Printf("Version: %d\n", c.Version)
+	fmt.Printf("NodeCount: %d\n", c.NodeCount)
+	fmt.Printf("RootHash: %s\n", c.RootHash())
+	fmt.Printf("Tries:\n")
+	for _, t := range c.Tries {
+		fmt.Printf("  - RootHash: %s\n", t.RootHash)
+		fmt.Printf("    RootIndex: %d\n", t.RootIndex)
+		fmt.Printf("    RegisterCount: %d\n", t.RegisterCount)
+		fmt.Printf("    RegisterSize: %d\n", t.RegisterSize)
+	}
+	fmt.Printf("Files:\n")
+	for _, f := range c.Files {
+		fmt.Printf("  - Name: %s\n", f.Name)
+		fmt.Printf("    Size: %d\n", f.Size)
+		fmt.Printf("    Checksum: %08x\n", f.Checksum)
+		if f.NodeCount > 0 {
+			fmt.Printf("    NodeCount: %d\n", f.NodeCount)
+		}
+	}
+}
+
+// readV5 reads a single file checkpoint. The tries are stored at the end of the file, followed by
+// the node and trie counts and the file checksum.
+func readV5(f file, location string) (*Checkpoint, error) {
+	nodeCount, tries, checksum, err := readTries(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Checkpoint{
+		Version:   VersionV5,
+		Files:     []File{{Name: location, Size: f.Size(), Checksum: checksum, NodeCount: nodeCount}},
+		NodeCount: nodeCount,
+		Tries:     tries,
+	}, nil
+}
+
+// readV6 reads a checkpoint split into a header file, subtrie files and a top level tries file.
+// The header file lists the checksums of the other files, which are checked against the checksums
+// stored in their footers.
+func readV6(src source, header file, location string) (*Checkpoint, error) {
+	subtrieChecksums, topTriesChecksum, headerChecksum, err := readV6Header(header)
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint header: %w", err)
+	}
+
+	c := &Checkpoint{
+		Version: VersionV6,
+		Files:   []File{{Name: location, Size: header.Size(), Checksum: headerChecksum}},
+	}
+
+	for i, expected := range subtrieChecksums {
+		name := partName(location, i)
+
+		nodeCount, checksum, size, err := readSubtrieFile(src, name)
+		if err != nil {
+			return nil, fmt.Errorf("error reading subtrie file %s: %w", name, err)
+		}
+		if checksum != expected {
+			return nil, fmt.Errorf("subtrie file %s has checksum %08x, expected %08x", name, checksum, expected)
+		}
+
+		c.Files = append(c.Files, File{Name: name, Size: size, Checksum: checksum, NodeCount: nodeCount})
+		c.NodeCount += nodeCount
+	}
+
+	name := partName(location, subtrieCount)
+
+	f, err := src.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	err = readHeaderVersion(f, magicTopTries, VersionV6)
+	if err != nil {
+		return nil, fmt.Errorf("error reading top tries file %s: %w", name, err)
+	}
+
+	nodeCount, tries, checksum, err := readTries(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading top tries file %s: %w", name, err)
+	}
+	if checksum != topTriesChecksum {
+		return nil, fmt.Errorf("top tries file %s has checksum %08x, expected %08x", name, checksum, topTriesChecksum)
+	}
+
+	c.Files = append(c.Files, File{Name: name, Size: f.Size(), Checksum: checksum, NodeCount: nodeCount})
+	c.NodeCount += nodeCount
+	c.Tries = tries
+
+	return c, nil
+}
+
+// readV6Header reads the V6 header file, which contains the subtrie file checksums, the top tries
+// file checksum, and its own checksum. The whole file is read to verify its checksum.
+func readV6Header(f file) ([]uint32, uint32, uint32, error) {
+	if f.Size() != v6HeaderFileSize {
+		return nil, 0, 0, fmt.Errorf("unexpected header file size: %d", f.Size())
+	}
+
+	data := make([]byte, f.Size())
+	_, err := f.ReadAt(data, 0)
+	if err != nil && err != io.EOF {
+		return nil, 0, 0, err
+	}
+
+	r := bytes.NewReader(data[encodedHeaderSize:])
+
+	var count uint16
+	err = binary.Read(r, binary.BigEndian, &count)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if count != subtrieCount {
+		return nil, 0, 0, fmt.Errorf("unexpected subtrie count: %d", count)
+	}
+
+	subtrieChecksums := make([]uint32, count)
+	var topTriesChecksum, checksum uint32
+	for _, value := range []any{subtrieChecksums, &topTriesChecksum, &checksum} {
+		err = binary.Read(r, binary.BigEndian, value)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	if actual := crc32.Checksum(data[:len(data)-encodedChecksumSize], crc32Table); actual != checksum {
+		return nil, 0, 0, fmt.Errorf("header checksum mismatch: expected %08x, got %08x", checksum, actual)
+	}
+
+	return subtrieChecksums, topTriesChecksum, checksum, nil
+}
+
+// readSubtrieFile reads the node count and checksum from a subtrie file's footer.
+func readSubtrieFile(src source, name string) (uint64, uint32, int64, error) {
+	f, err := src.open(name)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer f.Close()
+
+	err = readHeaderVersion(f, magicSubtrie, VersionV6)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	footer, err := readTail(f, encodedNodeCountSize+encodedChecksumSize)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	nodeCount := binary.BigEndian.Uint64(footer)
+	checksum := binary.BigEndian.Uint32(footer[encodedNodeCountSize:])
+
+	return nodeCount, checksum, f.Size(), nil
+}
+
+// readTries reads the tries, node count and checksum from the end of a file. The footer contains
+// the node count and trie count, and is preceded by the encoded tries.
+func readTries(f file) (uint64, []Trie, uint32, error) {
+	footerSize := int64(encodedNodeCountSize + encodedTrieCountSize + encodedChecksumSize)
+
+	footer, err := readTail(f, footerSize)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+
+	nodeCount := binary.BigEndian.Uint64(footer)
+	trieCount := int64(binary.BigEndian.Uint16(footer[encodedNodeCountSize:]))
+	checksum := binary.BigEndian.Uint32(footer[encodedNodeCountSize+encodedTrieCountSize:])
+
+	triesSize := trieCount * encodedTrieSize
+	if f.Size() < encodedHeaderSize+triesSize+footerSize {
+		return 0, nil, 0, fmt.Errorf("file is too small for %d tries", trieCount)
+	}
+
+	data := make([]byte, triesSize)
+	_, err = f.ReadAt(data, f.Size()-footerSize-triesSize)
+	if err != nil && err != io.EOF {
+		return 0, nil, 0, err
+	}
+
+	tries := make([]Trie, trieCount)
+	for i := range tries {
+		encoded := data[i*encodedTrieSize : (i+1)*encodedTrieSize]
+		tries[i] = Trie{
+			RootIndex:     binary.BigEndian.Uint64(encoded[0:8]),
+			RegisterCount: binary.BigEndian.Uint64(encoded[8:16]),
+			RegisterSize:  binary.BigEndian.Uint64(encoded[16:24]),
+			RootHash:      hex.EncodeToString(encoded[24:56]),
+		}
+	}
+
+	return nodeCount, tries, checksum, nil
+}
+
+// readHeader checks the magic bytes at the start of a file, and returns the version.
+func readHeader(f file, magic uint16) (uint16, error) {
+	if f.Size() < encodedHeaderSize {
+		return 0, fmt.Errorf("file is too small")
+	}
+
+	header := make([]byte, encodedHeaderSize)
+	_, err := f.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	if actual := binary.BigEndian.Uint16(header); actual != magic {
+		return 0, fmt.Errorf("unexpected magic bytes: expected %04x, got %04x", magic, actual)
+	}
+
+	return binary.BigEndian.Uint16(header[2:]), nil
+}
+
+// readHeaderVersion checks the magic bytes and version at the start of a file.
+func readHeaderVersion(f file, magic, version uint16) error {
+	actual, err := readHeader(f, magic)
+	if err != nil {
+		return err
+	}
+	if actual != version {
+		return fmt.Errorf("unexpected version: expected %d, got %d", version, actual)
+	}
+	return nil
+}
+
+// readTail reads the last n bytes of a file.
+func readTail(f file, n int64) ([]byte, error) {
+	if f.Size() < encodedHeaderSize+n {
+		return nil, fmt.Errorf("file is too small")
+	}
+
+	data := make([]byte, n)
+	_, err := f.ReadAt(data, f.Size()-n)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
+
+// partName returns the name of a V6 checkpoint part file.
+func partName(location string, index int) string {
+	return fmt.Sprintf("%s.%03d", location, index)
+}
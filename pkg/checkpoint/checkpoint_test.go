@@ -0,0 +1,334 @@
+package checkpoint
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testTries = []Trie{
+	{RootIndex: 7, RegisterCount: 100, RegisterSize: 4096, RootHash: strings.Repeat("a1", 32)},
+	{RootIndex: 12, RegisterCount: 150, RegisterSize: 8192, RootHash: strings.Repeat("b2", 32)},
+}
+
+// encodeHeader returns the magic bytes and version at the start of a checkpoint file.
+func encodeHeader(magic, version uint16) []byte {
+	return binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16(nil, magic), version)
+}
+
+func encodeTries(t *testing.T, tries []Trie) []byte {
+	t.Helper()
+
+	var data []byte
+	for _, trie := range tries {
+		data = binary.BigEndian.AppendUint64(data, trie.RootIndex)
+		data = binary.BigEndian.AppendUint64(data, trie.RegisterCount)
+		data = binary.BigEndian.AppendUint64(data, trie.RegisterSize)
+		hash, err := hex.DecodeString(trie.RootHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, hash...)
+	}
+	return data
+}
+
+// appendChecksum appends the crc32 of the data, as flow-go does at the end of each file.
+func appendChecksum(data []byte) []byte {
+	return binary.BigEndian.AppendUint32(data, crc32.Checksum(data, crc32Table))
+}
+
+// updateChecksum recomputes the crc32 at the end of the data after it was modified.
+func updateChecksum(data []byte) {
+	binary.BigEndian.PutUint32(data[len(data)-encodedChecksumSize:], crc32.Checksum(data[:len(data)-encodedChecksumSize], crc32Table))
+}
+
+func fileChecksum(data []byte) uint32 {
+	return binary.BigEndian.Uint32(data[len(data)-encodedChecksumSize:])
+}
+
+// triesFile returns a file with encoded nodes followed by the tries and the tries footer.
+func triesFile(t *testing.T, magic, version uint16, nodeCount uint64, tries []Trie) []byte {
+	t.Helper()
+
+	data := encodeHeader(magic, version)
+	data = append(data, make([]byte, nodeCount*3)...) // placeholder for the encoded nodes
+	data = append(data, encodeTries(t, tries)...)
+	data = binary.BigEndian.AppendUint64(data, nodeCount)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(tries)))
+	return appendChecksum(data)
+}
+
+func subtrieFile(nodeCount uint64) []byte {
+	data := encodeHeader(magicSubtrie, VersionV6)
+	data = append(data, make([]byte, nodeCount*3)...)
+	data = binary.BigEndian.AppendUint64(data, nodeCount)
+	return appendChecksum(data)
+}
+
+// v6Files returns the files of a V6 checkpoint, indexed by part number. The header file is at
+// index -1.
+func v6Files(t *testing.T) map[int][]byte {
+	t.Helper()
+
+	files := make(map[int][]byte, subtrieCount+2)
+	header := encodeHeader(magicHeader, VersionV6)
+	header = binary.BigEndian.AppendUint16(header, subtrieCount)
+	for i := 0; i < subtrieCount; i++ {
+		files[i] = subtrieFile(uint64(i + 1))
+		header = binary.BigEndian.AppendUint32(header, fileChecksum(files[i]))
+	}
+	files[subtrieCount] = triesFile(t, magicTopTries, VersionV6, 5, testTries)
+	header = binary.BigEndian.AppendUint32(header, fileChecksum(files[subtrieCount]))
+	files[-1] = appendChecksum(header)
+
+	return files
+}
+
+func writeFiles(t *testing.T, location string, files map[int][]byte) {
+	t.Helper()
+
+	for i, data := range files {
+		name := location
+		if i >= 0 {
+			name = partName(location, i)
+		}
+		if err := os.WriteFile(name, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadV5(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "root.checkpoint")
+	data := triesFile(t, magicHeader, VersionV5, 42, testTries)
+	writeFiles(t, location, map[int][]byte{-1: data})
+
+	c, err := Load(location)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if c.Version != VersionV5 || c.NodeCount != 42 {
+		t.Errorf("unexpected checkpoint %+v", c)
+	}
+	if len(c.Tries) != 2 || c.Tries[0] != testTries[0] || c.Tries[1] != testTries[1] {
+		t.Errorf("unexpected tries %+v", c.Tries)
+	}
+	want := File{Name: location, Size: int64(len(data)), Checksum: fileChecksum(data), NodeCount: 42}
+	if len(c.Files) != 1 || c.Files[0] != want {
+		t.Errorf("expected files [%+v], got %+v", want, c.Files)
+	}
+	if c.RootHash() != testTries[1].RootHash {
+		t.Errorf("expected the root hash of the last trie, got %s", c.RootHash())
+	}
+}
+
+func TestLoadV6(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "root.checkpoint")
+	files := v6Files(t)
+	writeFiles(t, location, files)
+
+	c, err := Load(location)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertV6(t, c, location, files)
+}
+
+func TestLoadV6HTTP(t *testing.T) {
+	dir := t.TempDir()
+	files := v6Files(t)
+	writeFiles(t, filepath.Join(dir, "root.checkpoint"), files)
+
+	// only the headers and footers are read, using range requests
+	var fullReads int
+	fs := http.FileServer(http.Dir(dir))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.Header.Get("Range") == "" {
+			fullReads++
+		}
+		fs.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	location := ts.URL + "/root.checkpoint"
+	c, err := Load(location)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertV6(t, c, location, files)
+
+	if fullReads > 0 {
+		t.Errorf("expected only range requests, got %d full reads", fullReads)
+	}
+}
+
+func assertV6(t *testing.T, c *Checkpoint, location string, files map[int][]byte) {
+	t.Helper()
+
+	// the subtrie files hold 1 to 16 nodes, and the top tries file 5
+	if c.Version != VersionV6 || c.NodeCount != 16*17/2+5 {
+		t.Errorf("unexpected checkpoint version %d with %d nodes", c.Version, c.NodeCount)
+	}
+	if len(c.Tries) != 2 || c.Tries[1] != testTries[1] {
+		t.Errorf("unexpected tries %+v", c.Tries)
+	}
+	if len(c.Files) != subtrieCount+2 {
+		t.Fatalf("expected %d files, got %d", subtrieCount+2, len(c.Files))
+	}
+
+	header := File{Name: location, Size: v6HeaderFileSize, Checksum: fileChecksum(files[-1])}
+	if c.Files[0] != header {
+		t.Errorf("expected header file %+v, got %+v", header, c.Files[0])
+	}
+	for i := 0; i <= subtrieCount; i++ {
+		want := File{
+			Name:      partName(location, i),
+			Size:      int64(len(files[i])),
+			Checksum:  fileChecksum(files[i]),
+			NodeCount: uint64(i + 1),
+		}
+		if i == subtrieCount {
+			want.NodeCount = 5
+		}
+		if c.Files[i+1] != want {
+			t.Errorf("expected file %+v, got %+v", want, c.Files[i+1])
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(files map[int][]byte)
+		err    string
+	}{
+		{
+			name:   "empty file",
+			modify: func(files map[int][]byte) { files[-1] = nil },
+			err:    "file is too small",
+		},
+		{
+			name:   "magic bytes",
+			modify: func(files map[int][]byte) { files[-1][0] = 0xff },
+			err:    "unexpected magic bytes: expected 2137",
+		},
+		{
+			name:   "unsupported version",
+			modify: func(files map[int][]byte) { files[-1][3] = 0x04 },
+			err:    "unsupported checkpoint version: 4",
+		},
+		{
+			name: "v5 truncated tries",
+			modify: func(files map[int][]byte) {
+				// a V5 file whose footer claims more tries than the file holds
+				data := encodeHeader(magicHeader, VersionV5)
+				data = binary.BigEndian.AppendUint64(data, 1)
+				data = binary.BigEndian.AppendUint16(data, 3)
+				files[-1] = appendChecksum(data)
+				for i := 0; i <= subtrieCount; i++ {
+					delete(files, i)
+				}
+			},
+			err: "file is too small for 3 tries",
+		},
+		{
+			name:   "header file size",
+			modify: func(files map[int][]byte) { files[-1] = append(files[-1], 0) },
+			err:    "unexpected header file size",
+		},
+		{
+			name:   "header checksum",
+			modify: func(files map[int][]byte) { files[-1][10] ^= 0xff },
+			err:    "header checksum mismatch",
+		},
+		{
+			name: "subtrie count",
+			modify: func(files map[int][]byte) {
+				binary.BigEndian.PutUint16(files[-1][encodedHeaderSize:], 15)
+				updateChecksum(files[-1])
+			},
+			err: "unexpected subtrie count: 15",
+		},
+		{
+			name: "subtrie checksum",
+			modify: func(files map[int][]byte) {
+				files[3] = subtrieFile(100)
+			},
+			err: "root.checkpoint.003 has checksum",
+		},
+		{
+			name:   "subtrie magic bytes",
+			modify: func(files map[int][]byte) { files[0][1] = 0x37 },
+			err:    "error reading subtrie file",
+		},
+		{
+			name: "subtrie version",
+			modify: func(files map[int][]byte) {
+				files[5][3] = byte(VersionV5)
+			},
+			err: "unexpected version: expected 6, got 5",
+		},
+		{
+			name:   "missing subtrie file",
+			modify: func(files map[int][]byte) { delete(files, 7) },
+			err:    "root.checkpoint.007",
+		},
+		{
+			name: "top tries checksum",
+			modify: func(files map[int][]byte) {
+				files[subtrieCount] = triesFile(t, magicTopTries, VersionV6, 6, testTries)
+			},
+			err: "top tries file",
+		},
+		{
+			name: "top tries magic bytes",
+			modify: func(files map[int][]byte) {
+				files[subtrieCount][1] = 0x36
+			},
+			err: "unexpected magic bytes: expected 2135",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			location := filepath.Join(t.TempDir(), "root.checkpoint")
+			files := v6Files(t)
+			test.modify(files)
+			writeFiles(t, location, files)
+
+			_, err := Load(location)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected an error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRootHash(t *testing.T) {
+	c := &Checkpoint{Tries: testTries}
+
+	for _, commitment := range []string{
+		testTries[1].RootHash,
+		"0x" + testTries[1].RootHash,
+		strings.ToUpper(testTries[1].RootHash),
+	} {
+		if err := c.VerifyRootHash(commitment); err != nil {
+			t.Errorf("%s: unexpected error: %v", commitment, err)
+		}
+	}
+
+	if err := c.VerifyRootHash(testTries[0].RootHash); err == nil {
+		t.Error("expected a mismatch for the root hash of another trie")
+	}
+	if err := (&Checkpoint{}).VerifyRootHash(testTries[1].RootHash); err == nil {
+		t.Error("expected a mismatch for a checkpoint without tries")
+	}
+}
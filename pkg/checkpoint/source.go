@@ -0,0 +1,118 @@
+package checkpoint
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const requestTimeout = time.Second * 120
+
+// file is a checkpoint file that supports random access.
+type file interface {
+	io.ReaderAt
+	io.Closer
+	Size() int64
+}
+
+// source opens checkpoint files from the local filesystem or over http.
+type source interface {
+	open(name string) (file, error)
+}
+
+func newSource(location string) source {
+	if strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://") {
+		return &httpSource{client: &http.Client{Timeout: requestTimeout}}
+	}
+	return localSource{}
+}
+
+type localSource struct{}
+
+type localFile struct {
+	*os.File
+	size int64
+}
+
+func (localSource) open(name string) (file, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file (path=%s): %w", name, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error reading file (path=%s): %w", name, err)
+	}
+
+	return &localFile{File: f, size: info.Size()}, nil
+}
+
+func (f *localFile) Size() int64 {
+	return f.size
+}
+
+// httpSource reads files over http using range requests, so only the requested parts of the
+// files are downloaded.
+type httpSource struct {
+	client *http.Client
+}
+
+type httpFile struct {
+	client *http.Client
+	url    string
+	size   int64
+}
+
+func (s *httpSource) open(url string) (file, error) {
+	res, err := s.client.Head(url)
+	if err != nil {
+		return nil, fmt.Errorf("error getting data (url=%s): %w", url, err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting data (url=%s): unexpected status %s", url, res.Status)
+	}
+	if res.ContentLength < 0 {
+		return nil, fmt.Errorf("error getting data (url=%s): unknown file size", url)
+	}
+
+	return &httpFile{client: s.client, url: url, size: res.ContentLength}, nil
+}
+
+func (f *httpFile) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, f.url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error getting data (url=%s): %w", f.url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("error getting data (url=%s): unexpected status %s", f.url, res.Status)
+	}
+
+	return io.ReadFull(res.Body, p)
+}
+
+func (f *httpFile) Size() int64 {
+	return f.size
+}
+
+func (f *httpFile) Close() error {
+	return nil
+}
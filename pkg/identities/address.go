@@ -0,0 +1,103 @@
+package identities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Resolver resolves host names to IP addresses. *net.Resolver implements Resolver.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// splitAddress splits an address into its normalized host and port. Addresses without a port,
+// including bare IPv6 addresses, return an empty port.
+func splitAddress(address string) (host, port string) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, ""
+	}
+	return normalizeHost(host), port
+}
+
+// normalizeHost returns the canonical form of a host. IP addresses are formatted in their standard
+// form, and DNS names are lowercased without a trailing dot.
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// normalizeAddress returns the canonical form of a host:port address.
+func normalizeAddress(address string) string {
+	host, port := splitAddress(address)
+	if port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// matchAddress returns true if the node address matches the query. The query may be a full
+// host:port address, or just the host. IPv6 addresses may be bracketed, and DNS names are matched
+// case-insensitively.
+func matchAddress(nodeAddress, query string) bool {
+	nodeHost, nodePort := splitAddress(nodeAddress)
+	queryHost, queryPort := splitAddress(query)
+
+	if nodeHost != queryHost {
+		return false
+	}
+	return queryPort == "" || queryPort == nodePort
+}
+
+// ByResolvedAddress returns the node with the given address, like ByAddress. If no node matches
+// directly and the address is an IP, the DNS names of the nodes are resolved with the resolver to
+// find a node whose name resolves to the IP. If resolver is nil, net.DefaultResolver is used.
+// Lookup errors are only returned if no node matches.
+func (l IdentityList) ByResolvedAddress(ctx context.Context, address string, resolver Resolver) (*NodeInfo, error) {
+	if node := l.ByAddress(address); node != nil {
+		return node, nil
+	}
+
+	queryHost, queryPort := splitAddress(address)
+	queryIP := net.ParseIP(queryHost)
+	if queryIP == nil {
+		return nil, nil
+	}
+
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	var errs []error
+	resolved := make(map[string][]string)
+	for i := range l {
+		host, port := splitAddress(l[i].Address)
+		if net.ParseIP(host) != nil || (queryPort != "" && queryPort != port) {
+			continue
+		}
+
+		ips, ok := resolved[host]
+		if !ok {
+			var err error
+			ips, err = resolver.LookupHost(ctx, host)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error resolving %s: %w", host, err))
+			}
+			resolved[host] = ips
+		}
+
+		for _, ip := range ips {
+			if queryIP.Equal(net.ParseIP(ip)) {
+				return &l[i], nil
+			}
+		}
+	}
+
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,147 @@
+package identities
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeResolver resolves hosts from a map, and counts lookups.
+type fakeResolver struct {
+	hosts   map[string][]string
+	errs    map[string]error
+	lookups map[string]int
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if r.lookups == nil {
+		r.lookups = make(map[string]int)
+	}
+	r.lookups[host]++
+
+	if err := r.errs[host]; err != nil {
+		return nil, err
+	}
+	return r.hosts[host], nil
+}
+
+func addressTestList() IdentityList {
+	return IdentityList{
+		{Role: RoleAccess, NodeID: "a1", Address: "access-001.mainnet.nodes.onflow.org:3569"},
+		{Role: RoleAccess, NodeID: "a2", Address: "Access-002.mainnet.nodes.onflow.org.:3569"},
+		{Role: RoleExecution, NodeID: "e1", Address: "[2001:db8::1]:3569"},
+		{Role: RoleConsensus, NodeID: "c1", Address: "10.0.0.5:3569"},
+	}
+}
+
+func TestByResolvedAddress(t *testing.T) {
+	resolver := &fakeResolver{
+		hosts: map[string][]string{
+			"access-001.mainnet.nodes.onflow.org": {"192.0.2.1"},
+			"access-002.mainnet.nodes.onflow.org": {"192.0.2.2", "2001:db8::2"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		{"resolved ip", "192.0.2.1", "a1"},
+		{"resolved ip and port", "192.0.2.2:3569", "a2"},
+		{"resolved ipv6", "[2001:db8:0::2]:3569", "a2"},
+		{"direct dns match", "ACCESS-001.mainnet.nodes.onflow.org:3569", "a1"},
+		{"direct ip match", "10.0.0.5", "c1"},
+		{"direct ipv6 match", "2001:db8:0:0::1", "e1"},
+		{"port mismatch", "192.0.2.1:9000", ""},
+		{"unknown ip", "192.0.2.99", ""},
+		{"unknown dns name", "unknown.example.com", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node, err := addressTestList().ByResolvedAddress(context.Background(), test.address, resolver)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.want == "" {
+				if node != nil {
+					t.Errorf("expected no match, got %s", node.NodeID)
+				}
+				return
+			}
+			if node == nil || node.NodeID != test.want {
+				t.Errorf("expected %s, got %v", test.want, node)
+			}
+		})
+	}
+}
+
+func TestByResolvedAddressCachesLookups(t *testing.T) {
+	list := append(addressTestList(), NodeInfo{NodeID: "a3", Address: "access-001.mainnet.nodes.onflow.org:3570"})
+	resolver := &fakeResolver{}
+
+	node, err := list.ByResolvedAddress(context.Background(), "192.0.2.50", resolver)
+	if err != nil || node != nil {
+		t.Fatalf("expected no match and no error, got %v, %v", node, err)
+	}
+	if resolver.lookups["access-001.mainnet.nodes.onflow.org"] != 1 {
+		t.Errorf("expected one lookup per host, got %d", resolver.lookups["access-001.mainnet.nodes.onflow.org"])
+	}
+	if resolver.lookups["10.0.0.5"] != 0 {
+		t.Error("ip addresses should not be resolved")
+	}
+}
+
+func TestByResolvedAddressResolverError(t *testing.T) {
+	lookupErr := errors.New("no such host")
+	resolver := &fakeResolver{
+		hosts: map[string][]string{
+			"access-002.mainnet.nodes.onflow.org": {"192.0.2.2"},
+		},
+		errs: map[string]error{
+			"access-001.mainnet.nodes.onflow.org": lookupErr,
+		},
+	}
+
+	// errors are only returned if no node matches
+	node, err := addressTestList().ByResolvedAddress(context.Background(), "192.0.2.2", resolver)
+	if err != nil {
+		t.Fatalf("unexpected error when a node matched: %v", err)
+	}
+	if node == nil || node.NodeID != "a2" {
+		t.Fatalf("expected a2, got %v", node)
+	}
+
+	node, err = addressTestList().ByResolvedAddress(context.Background(), "192.0.2.99", resolver)
+	if node != nil {
+		t.Errorf("expected no match, got %s", node.NodeID)
+	}
+	if !errors.Is(err, lookupErr) {
+		t.Fatalf("expected the lookup error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "error resolving access-001.mainnet.nodes.onflow.org") {
+		t.Errorf("error does not name the host: %v", err)
+	}
+}
+
+func TestMatchAddress(t *testing.T) {
+	tests := []struct {
+		node, query string
+		want        bool
+	}{
+		{"node.example.com:3569", "node.example.com:3569", true},
+		{"node.example.com:3569", "NODE.example.com.", true},
+		{"node.example.com:3569", "node.example.com:3570", false},
+		{"[2001:db8::1]:3569", "[2001:0db8::1]", true},
+		{"[2001:db8::1]:3569", "2001:db8::1", true},
+		{"10.0.0.1:3569", "10.0.0.10:3569", false},
+	}
+
+	for _, test := range tests {
+		if got := matchAddress(test.node, test.query); got != test.want {
+			t.Errorf("matchAddress(%q, %q) = %v, expected %v", test.node, test.query, got, test.want)
+		}
+	}
+}
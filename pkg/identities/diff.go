@@ -0,0 +1,108 @@
+package identities
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ChangeKind is the type of change made to a node between two identity lists.
+type ChangeKind string
+
+const (
+	ChangeJoined     ChangeKind = "joined"
+	ChangeLeft       ChangeKind = "left"
+	ChangeRole       ChangeKind = "role"
+	ChangeAddress    ChangeKind = "address"
+	ChangeStake      ChangeKind = "stake"
+	ChangeNetworkKey ChangeKind = "networkKey"
+	ChangeStakingKey ChangeKind = "stakingKey"
+)
+
+// Change is a single change to a node. For joined nodes, New is the node's address, and for nodes
+// that left, Old is the node's address.
+type Change struct {
+	Kind   ChangeKind `json:"kind"`
+	NodeID string     `json:"nodeId"`
+	Role   Role       `json:"role"`
+	Old    string     `json:"old,omitempty"`
+	New    string     `json:"new,omitempty"`
+}
+
+// Changes is a list of changes between two identity lists.
+type Changes []Change
+
+// Diff returns the changes from the old list to the new list. Nodes are matched by node ID, and
+// changes are returned in canonical node ID order.
+func Diff(old, new IdentityList) Changes {
+	var changes Changes
+
+	oldIndex := NewIdentityIndex(old)
+	newIndex := NewIdentityIndex(new)
+
+	for _, node := range old.Union(new).Sort() {
+		before := oldIndex.ByNodeID(node.NodeID)
+		after := newIndex.ByNodeID(node.NodeID)
+
+		switch {
+		case before == nil:
+			changes = append(changes, Change{Kind: ChangeJoined, NodeID: after.NodeID, Role: after.Role, New: after.Address})
+		case after == nil:
+			changes = append(changes, Change{Kind: ChangeLeft, NodeID: before.NodeID, Role: before.Role, Old: before.Address})
+		default:
+			changes = append(changes, diffNode(*before, *after)...)
+		}
+	}
+
+	return changes
+}
+
+func diffNode(before, after NodeInfo) Changes {
+	var changes Changes
+	add := func(kind ChangeKind, old, new string) {
+		if old != new {
+			changes = append(changes, Change{Kind: kind, NodeID: after.NodeID, Role: after.Role, Old: old, New: new})
+		}
+	}
+
+	add(ChangeRole, string(before.Role), string(after.Role))
+	add(ChangeAddress, before.Address, after.Address)
+	add(ChangeStake, strconv.FormatUint(before.Stake, 10), strconv.FormatUint(after.Stake, 10))
+	add(ChangeNetworkKey, before.NetworkPubKey, after.NetworkPubKey)
+	add(ChangeStakingKey, before.StakingPubKey, after.StakingPubKey)
+
+	return changes
+}
+
+// String returns a one line description of the change.
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeJoined:
+		return fmt.Sprintf("%s %s (%s) %s", c.Kind, c.NodeID, c.Role, c.New)
+	case ChangeLeft:
+		return fmt.Sprintf("%s %s (%s) %s", c.Kind, c.NodeID, c.Role, c.Old)
+	}
+	return fmt.Sprintf("%s %s (%s): %s -> %s", c.Kind, c.NodeID, c.Role, c.Old, c.New)
+}
+
+// ByKind returns the changes of the given kind.
+func (c Changes) ByKind(kind ChangeKind) Changes {
+	var result Changes
+	for _, change := range c {
+		if change.Kind == kind {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// Print prints the changes to stdout.
+func (c Changes) Print() {
+	if len(c) == 0 {
+		fmt.Println("No identity changes")
+		return
+	}
+
+	for _, change := range c {
+		fmt.Println(change)
+	}
+}
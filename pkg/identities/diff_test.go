@@ -0,0 +1,123 @@
+package identities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func diffTestList() IdentityList {
+	return IdentityList{
+		{Role: RoleConsensus, NodeID: "01", Address: "consensus-1.example.com:3569", Stake: 100, NetworkPubKey: "aa", StakingPubKey: "bb"},
+		{Role: RoleAccess, NodeID: "02", Address: "access-1.example.com:3569", Stake: 200, NetworkPubKey: "cc", StakingPubKey: "dd"},
+		{Role: RoleCollection, NodeID: "03", Address: "collection-1.example.com:3569", Stake: 300, NetworkPubKey: "ee", StakingPubKey: "ff"},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(l IdentityList) IdentityList
+		want   Changes
+	}{
+		{
+			name:   "identical",
+			modify: func(l IdentityList) IdentityList { return l },
+		},
+		{
+			name: "reordered",
+			modify: func(l IdentityList) IdentityList {
+				return IdentityList{l[2], l[0], l[1]}
+			},
+		},
+		{
+			name: "joined",
+			modify: func(l IdentityList) IdentityList {
+				return append(l, NodeInfo{Role: RoleExecution, NodeID: "00", Address: "execution-1.example.com:3569"})
+			},
+			want: Changes{{Kind: ChangeJoined, NodeID: "00", Role: RoleExecution, New: "execution-1.example.com:3569"}},
+		},
+		{
+			name:   "left",
+			modify: func(l IdentityList) IdentityList { return l[1:] },
+			want:   Changes{{Kind: ChangeLeft, NodeID: "01", Role: RoleConsensus, Old: "consensus-1.example.com:3569"}},
+		},
+		{
+			name: "every field",
+			modify: func(l IdentityList) IdentityList {
+				l[1] = NodeInfo{Role: RoleExecution, NodeID: "02", Address: "execution-2.example.com:3569", Stake: 250, NetworkPubKey: "11", StakingPubKey: "22"}
+				return l
+			},
+			want: Changes{
+				{Kind: ChangeRole, NodeID: "02", Role: RoleExecution, Old: "access", New: "execution"},
+				{Kind: ChangeAddress, NodeID: "02", Role: RoleExecution, Old: "access-1.example.com:3569", New: "execution-2.example.com:3569"},
+				{Kind: ChangeStake, NodeID: "02", Role: RoleExecution, Old: "200", New: "250"},
+				{Kind: ChangeNetworkKey, NodeID: "02", Role: RoleExecution, Old: "cc", New: "11"},
+				{Kind: ChangeStakingKey, NodeID: "02", Role: RoleExecution, Old: "dd", New: "22"},
+			},
+		},
+		{
+			name: "changes are in node ID order",
+			modify: func(l IdentityList) IdentityList {
+				l[2].Stake = 0
+				l[0].Address = "consensus-2.example.com:3569"
+				return append(IdentityList{{Role: RoleAccess, NodeID: "04"}}, l[:2]...)
+			},
+			want: Changes{
+				{Kind: ChangeAddress, NodeID: "01", Role: RoleConsensus, Old: "consensus-1.example.com:3569", New: "consensus-2.example.com:3569"},
+				{Kind: ChangeLeft, NodeID: "03", Role: RoleCollection, Old: "collection-1.example.com:3569"},
+				{Kind: ChangeJoined, NodeID: "04", Role: RoleAccess},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Diff(diffTestList(), test.modify(diffTestList()))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %+v, got %+v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestChangesByKind(t *testing.T) {
+	changes := Changes{
+		{Kind: ChangeJoined, NodeID: "01"},
+		{Kind: ChangeStake, NodeID: "02"},
+		{Kind: ChangeJoined, NodeID: "03"},
+	}
+
+	want := Changes{changes[0], changes[2]}
+	if got := changes.ByKind(ChangeJoined); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if got := changes.ByKind(ChangeLeft); got != nil {
+		t.Errorf("expected no changes, got %+v", got)
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	tests := []struct {
+		change Change
+		want   string
+	}{
+		{
+			change: Change{Kind: ChangeJoined, NodeID: "01", Role: RoleAccess, New: "access-1.example.com:3569"},
+			want:   "joined 01 (access) access-1.example.com:3569",
+		},
+		{
+			change: Change{Kind: ChangeLeft, NodeID: "01", Role: RoleAccess, Old: "access-1.example.com:3569"},
+			want:   "left 01 (access) access-1.example.com:3569",
+		},
+		{
+			change: Change{Kind: ChangeStake, NodeID: "01", Role: RoleAccess, Old: "100", New: "200"},
+			want:   "stake 01 (access): 100 -> 200",
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.change.String(); got != test.want {
+			t.Errorf("expected %q, got %q", test.want, got)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package identities
+
+import (
+	"sort"
+	"strings"
+)
+
+// Filter selects nodes from an IdentityList.
+type Filter func(NodeInfo) bool
+
+// Filter returns the nodes that match all the filters.
+func (l IdentityList) Filter(filters ...Filter) IdentityList {
+	match := And(filters...)
+
+	var result IdentityList
+	for _, i := range l {
+		if match(i) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// And returns a filter that matches nodes matching all the filters.
+func And(filters ...Filter) Filter {
+	return func(n NodeInfo) bool {
+		for _, filter := range filters {
+			if !filter(n) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a filter that matches nodes matching any of the filters.
+func Or(filters ...Filter) Filter {
+	return func(n NodeInfo) bool {
+		for _, filter := range filters {
+			if filter(n) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a filter that matches nodes not matching the filter.
+func Not(filter Filter) Filter {
+	return func(n NodeInfo) bool {
+		return !filter(n)
+	}
+}
+
+// HasRole matches nodes with any of the roles. Roles are matched case-insensitively.
+func HasRole(roles ...Role) Filter {
+	return func(n NodeInfo) bool {
+		for _, role := range roles {
+			if strings.EqualFold(string(n.Role), string(role)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// HasNodeID matches nodes with any of the node IDs.
+func HasNodeID(nodeIDs ...string) Filter {
+	set := make(map[string]bool, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		set[nodeID] = true
+	}
+	return func(n NodeInfo) bool {
+		return set[n.NodeID]
+	}
+}
+
+// HasStakeBetween matches nodes with a stake between min and max, inclusive.
+func HasStakeBetween(min, max uint64) Filter {
+	return func(n NodeInfo) bool {
+		return n.Stake >= min && n.Stake <= max
+	}
+}
+
+// HasAddressSuffix matches nodes whose host ends with the suffix. Hosts are matched
+// case-insensitively.
+func HasAddressSuffix(suffix string) Filter {
+	suffix = strings.ToLower(suffix)
+	return func(n NodeInfo) bool {
+		host, _ := splitAddress(n.Address)
+		return strings.HasSuffix(host, suffix)
+	}
+}
+
+// InDomain matches nodes whose host is the domain or one of its subdomains.
+func InDomain(domain string) Filter {
+	domain = normalizeHost(domain)
+	return func(n NodeInfo) bool {
+		host, _ := splitAddress(n.Address)
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}
+}
+
+// Sort returns a copy of the list sorted in flow's canonical order by node ID.
+func (l IdentityList) Sort() IdentityList {
+	sorted := make(IdentityList, len(l))
+	copy(sorted, l)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return canonicalLess(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// IsCanonical returns true if the list is in flow's canonical order by node ID.
+func (l IdentityList) IsCanonical() bool {
+	return sort.SliceIsSorted(l, func(i, j int) bool {
+		return canonicalLess(l[i], l[j])
+	})
+}
+
+// canonicalLess orders node IDs by their bytes, which for hex encoded IDs is the same as ordering
+// the lowercase strings.
+func canonicalLess(a, b NodeInfo) bool {
+	return strings.ToLower(a.NodeID) < strings.ToLower(b.NodeID)
+}
+
+// TotalStake returns the total stake of all nodes in the list.
+func (l IdentityList) TotalStake() uint64 {
+	var total uint64
+	for _, i := range l {
+		total += i.Stake
+	}
+	return total
+}
+
+// StakeByRole returns the total stake of the nodes in the list for each role.
+func (l IdentityList) StakeByRole() map[Role]uint64 {
+	totals := make(map[Role]uint64)
+	for _, i := range l {
+		totals[i.Role] += i.Stake
+	}
+	return totals
+}
+
+// Union returns the nodes in either list. Nodes are matched by node ID, and the entry from l is
+// used for nodes in both lists.
+func (l IdentityList) Union(other IdentityList) IdentityList {
+	seen := make(map[string]bool, len(l)+len(other))
+	var result IdentityList
+	for _, list := range []IdentityList{l, other} {
+		for _, i := range list {
+			if seen[i.NodeID] {
+				continue
+			}
+			seen[i.NodeID] = true
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// Intersection returns the nodes in l that are also in other, matched by node ID.
+func (l IdentityList) Intersection(other IdentityList) IdentityList {
+	return l.Filter(HasNodeID(other.NodeIDs()...))
+}
+
+// Difference returns the nodes in l that are not in other, matched by node ID.
+func (l IdentityList) Difference(other IdentityList) IdentityList {
+	return l.Filter(Not(HasNodeID(other.NodeIDs()...)))
+}
+
+// NodeIDs returns the node IDs of all nodes in the list.
+func (l IdentityList) NodeIDs() []string {
+	ids := make([]string, len(l))
+	for i, n := range l {
+		ids[i] = n.NodeID
+	}
+	return ids
+}
@@ -0,0 +1,126 @@
+package identities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func filterTestList() IdentityList {
+	return IdentityList{
+		{Role: RoleConsensus, NodeID: "0c", Address: "consensus-001.mainnet.nodes.onflow.org:3569", Stake: 300},
+		{Role: RoleAccess, NodeID: "0A", Address: "access-001.mainnet.nodes.onflow.org:3569", Stake: 0},
+		{Role: RoleCollection, NodeID: "01", Address: "collection.example.com:3569", Stake: 100},
+		{Role: RoleExecution, NodeID: "0b", Address: "Exec.Mainnet.Nodes.Onflow.org:3569", Stake: 200},
+		{Role: RoleAccess, NodeID: "02", Address: "notonflow.org:3569", Stake: 50},
+	}
+}
+
+func TestFilter(t *testing.T) {
+	list := filterTestList()
+
+	tests := []struct {
+		name    string
+		filters []Filter
+		want    []string
+	}{
+		{name: "no filters", want: []string{"0c", "0A", "01", "0b", "02"}},
+		{name: "role", filters: []Filter{HasRole("ACCESS")}, want: []string{"0A", "02"}},
+		{name: "roles", filters: []Filter{HasRole(RoleCollection, RoleExecution)}, want: []string{"01", "0b"}},
+		{name: "node IDs", filters: []Filter{HasNodeID("01", "02", "ff")}, want: []string{"01", "02"}},
+		{name: "stake", filters: []Filter{HasStakeBetween(50, 200)}, want: []string{"01", "0b", "02"}},
+		{name: "address suffix", filters: []Filter{HasAddressSuffix("onflow.ORG")}, want: []string{"0c", "0A", "0b", "02"}},
+		{name: "domain", filters: []Filter{InDomain("Onflow.org.")}, want: []string{"0c", "0A", "0b"}},
+		{name: "all filters match", filters: []Filter{HasRole(RoleAccess), HasStakeBetween(1, 100)}, want: []string{"02"}},
+		{name: "or", filters: []Filter{Or(HasRole(RoleConsensus), HasStakeBetween(0, 0))}, want: []string{"0c", "0A"}},
+		{name: "not", filters: []Filter{Not(InDomain("onflow.org"))}, want: []string{"01", "02"}},
+		{name: "and", filters: []Filter{And(HasRole(RoleAccess), Not(HasNodeID("0A")))}, want: []string{"02"}},
+		{name: "no match", filters: []Filter{HasRole(RoleVerification)}, want: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := list.Filter(test.filters...).NodeIDs()
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestSort(t *testing.T) {
+	list := filterTestList()
+
+	sorted := list.Sort()
+	want := []string{"01", "02", "0A", "0b", "0c"}
+	if got := sorted.NodeIDs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if !sorted.IsCanonical() {
+		t.Error("expected the sorted list to be canonical")
+	}
+
+	// the list itself is not modified
+	if list.IsCanonical() || list[0].NodeID != "0c" {
+		t.Errorf("expected the original list to be unchanged, got %v", list.NodeIDs())
+	}
+	if !(IdentityList{}).IsCanonical() {
+		t.Error("expected an empty list to be canonical")
+	}
+}
+
+func TestStake(t *testing.T) {
+	list := filterTestList()
+
+	if total := list.TotalStake(); total != 650 {
+		t.Errorf("expected a total stake of 650, got %d", total)
+	}
+
+	want := map[Role]uint64{
+		RoleConsensus:  300,
+		RoleAccess:     50,
+		RoleCollection: 100,
+		RoleExecution:  200,
+	}
+	if got := list.StakeByRole(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	a := IdentityList{
+		{NodeID: "01", Stake: 1},
+		{NodeID: "02", Stake: 1},
+		{NodeID: "03", Stake: 1},
+	}
+	b := IdentityList{
+		{NodeID: "03", Stake: 2},
+		{NodeID: "04", Stake: 2},
+		{NodeID: "02", Stake: 2},
+	}
+
+	union := a.Union(b)
+	if got, want := union.NodeIDs(), []string{"01", "02", "03", "04"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("union: expected %v, got %v", want, got)
+	}
+	// nodes in both lists keep the entry from the receiver
+	if union[1].Stake != 1 || union[3].Stake != 2 {
+		t.Errorf("union: unexpected entries %+v", union)
+	}
+
+	if got, want := a.Intersection(b).NodeIDs(), []string{"02", "03"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("intersection: expected %v, got %v", want, got)
+	}
+	if got, want := a.Difference(b).NodeIDs(), []string{"01"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("difference: expected %v, got %v", want, got)
+	}
+	if got, want := b.Difference(a).NodeIDs(), []string{"04"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("difference: expected %v, got %v", want, got)
+	}
+
+	if got := a.Intersection(nil); len(got) != 0 {
+		t.Errorf("intersection with an empty list: expected no nodes, got %v", got.NodeIDs())
+	}
+	if got := a.Difference(nil).NodeIDs(); !reflect.DeepEqual(got, a.NodeIDs()) {
+		t.Errorf("difference with an empty list: expected %v, got %v", a.NodeIDs(), got)
+	}
+}
@@ -0,0 +1,444 @@
+package identities
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/peterargue/flow-info/internal"
+)
+
+// Format is a file format for node infos.
+type Format string
+
+const (
+	// FormatJSON is flow-go's node-infos.pub.json format.
+	FormatJSON     Format = "json"
+	FormatCSV      Format = "csv"
+	FormatYAML     Format = "yaml"
+	FormatMarkdown Format = "markdown"
+)
+
+// csvHeader is the list of columns written to csv files. The column names match the json field names,
+// and the Stake column is named after the codec's stake field.
+var csvHeader = []string{"Role", "Address", "NodeID", "Stake", "NetworkPubKey", "StakingPubKey", "StakingPoP"}
+
+// StakeField is the field name a node's stake is recorded under. Older node-infos files use Stake,
+// and newer ones use Weight.
+type StakeField string
+
+const (
+	StakeFieldStake  StakeField = "Stake"
+	StakeFieldWeight StakeField = "Weight"
+)
+
+// ParseStakeField parses a stake field from its name. Names are matched case-insensitively.
+func ParseStakeField(name string) (StakeField, error) {
+	for _, field := range []StakeField{StakeFieldStake, StakeFieldWeight} {
+		if strings.EqualFold(name, string(field)) {
+			return field, nil
+		}
+	}
+	return "", fmt.Errorf("invalid stake field: %s", name)
+}
+
+// Codec reads and writes node infos in a format.
+type Codec struct {
+	Format Format
+
+	// StakeField is the field name stakes are written under, and defaults to Stake. Read sets it
+	// to the name used by the input, so lists written with the same codec keep the name.
+	StakeField StakeField
+}
+
+// ParseFormat parses a format from its name or file extension.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "json":
+		return FormatJSON, nil
+	case "csv":
+		return FormatCSV, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	}
+	return "", fmt.Errorf("invalid format: %s", name)
+}
+
+// FormatFromPath returns the format matching the extension of a file path or url. Paths with an
+// unknown extension use the json format.
+func FormatFromPath(location string) Format {
+	if u, err := url.Parse(location); err == nil && u.Scheme != "" {
+		location = u.Path
+	}
+
+	format, err := ParseFormat(path.Ext(location))
+	if err != nil {
+		return FormatJSON
+	}
+	return format
+}
+
+// nodeInfoFields is the encoding of a node info in json and yaml. Only one of Stake and Weight is set.
+type nodeInfoFields struct {
+	Role          Role    `yaml:"Role"`
+	Address       string  `yaml:"Address"`
+	NodeID        string  `yaml:"NodeID"`
+	Stake         *uint64 `json:",omitempty" yaml:"Stake,omitempty"`
+	Weight        *uint64 `json:",omitempty" yaml:"Weight,omitempty"`
+	NetworkPubKey string  `yaml:"NetworkPubKey"`
+	StakingPubKey string  `yaml:"StakingPubKey"`
+	StakingPoP    string  `json:",omitempty" yaml:"StakingPoP,omitempty"`
+}
+
+func (l IdentityList) fields(stakeField StakeField) []nodeInfoFields {
+	fields := make([]nodeInfoFields, len(l))
+	for i, n := range l {
+		fields[i] = nodeInfoFields{
+			Role:          n.Role,
+			Address:       n.Address,
+			NodeID:        n.NodeID,
+			NetworkPubKey: n.NetworkPubKey,
+			StakingPubKey: n.StakingPubKey,
+			StakingPoP:    n.StakingPoP,
+		}
+
+		stake := n.Stake
+		if stakeField == StakeFieldWeight {
+			fields[i].Weight = &stake
+		} else {
+			fields[i].Stake = &stake
+		}
+	}
+	return fields
+}
+
+// identityListFromFields returns the list of node infos, and the name of the field stakes were
+// read from. The name is empty if no node has a stake.
+func identityListFromFields(fields []nodeInfoFields) (IdentityList, StakeField) {
+	var stakeField StakeField
+
+	l := make(IdentityList, len(fields))
+	for i, f := range fields {
+		l[i] = NodeInfo{
+			Role:          f.Role,
+			Address:       f.Address,
+			NodeID:        f.NodeID,
+			NetworkPubKey: f.NetworkPubKey,
+			StakingPubKey: f.StakingPubKey,
+			StakingPoP:    f.StakingPoP,
+		}
+
+		switch {
+		case f.Weight != nil:
+			l[i].Stake = *f.Weight
+			stakeField = StakeFieldWeight
+		case f.Stake != nil:
+			l[i].Stake = *f.Stake
+			if stakeField == "" {
+				stakeField = StakeFieldStake
+			}
+		}
+	}
+	return l, stakeField
+}
+
+// ReadNodeInfo reads node infos in the given format. Stakes are read from either a Stake or a
+// Weight field.
+func ReadNodeInfo(r io.Reader, format Format) (IdentityList, error) {
+	codec := Codec{Format: format}
+	return codec.Read(r)
+}
+
+// Read reads node infos in the codec's format, and sets the codec's stake field to the name used
+// by the input. Markdown is output only.
+func (c *Codec) Read(r io.Reader) (IdentityList, error) {
+	var nodeInfos IdentityList
+	var stakeField StakeField
+	var err error
+
+	switch c.Format {
+	case FormatJSON:
+		nodeInfos, stakeField, err = readJSON(r)
+	case FormatCSV:
+		nodeInfos, stakeField, err = readCSV(r)
+	case FormatYAML:
+		nodeInfos, stakeField, err = readYAML(r)
+	default:
+		return nil, fmt.Errorf("reading %s node infos is not supported", c.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if stakeField != "" {
+		c.StakeField = stakeField
+	}
+	return nodeInfos, nil
+}
+
+func readJSON(r io.Reader) (IdentityList, StakeField, error) {
+	var fields []nodeInfoFields
+	err := json.NewDecoder(r).Decode(&fields)
+	if err != nil {
+		return nil, "", fmt.Errorf("error unmarshalling node-infos json: %w", err)
+	}
+	if fields == nil {
+		return nil, "", nil
+	}
+
+	nodeInfos, stakeField := identityListFromFields(fields)
+	return nodeInfos, stakeField, nil
+}
+
+func readYAML(r io.Reader) (IdentityList, StakeField, error) {
+	var fields []nodeInfoFields
+	err := yaml.NewDecoder(r).Decode(&fields)
+	if err != nil && err != io.EOF {
+		return nil, "", fmt.Errorf("error unmarshalling node-infos yaml: %w", err)
+	}
+	if fields == nil {
+		return nil, "", nil
+	}
+
+	nodeInfos, stakeField := identityListFromFields(fields)
+	return nodeInfos, stakeField, nil
+}
+
+// readCSV reads node infos from csv. The first row is the header, and columns are matched to
+// fields by name, ignoring case, spaces, dashes and underscores. The stake is read from either a
+// Stake or a Weight column.
+func readCSV(r io.Reader) (IdentityList, StakeField, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading node-infos csv header: %w", err)
+	}
+
+	var stakeField StakeField
+	columns := make([]string, len(header))
+	for i, name := range header {
+		if i == 0 {
+			// spreadsheet exports often start with a utf-8 byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		column, err := csvColumn(name)
+		if err != nil {
+			return nil, "", err
+		}
+		if column == string(StakeFieldStake) || column == string(StakeFieldWeight) {
+			stakeField = StakeField(column)
+		}
+		columns[i] = column
+	}
+
+	var nodeInfos IdentityList
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading node-infos csv: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		var node NodeInfo
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if err := node.setField(columns[i], value); err != nil {
+				return nil, "", fmt.Errorf("error reading node-infos csv line %d: %w", line, err)
+			}
+		}
+		nodeInfos = append(nodeInfos, node)
+	}
+
+	return nodeInfos, stakeField, nil
+}
+
+// csvColumn returns the field name for a csv header column.
+func csvColumn(name string) (string, error) {
+	normalized := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+	if normalized == "weight" {
+		return "Weight", nil
+	}
+	for _, column := range csvHeader {
+		if normalized == strings.ToLower(column) {
+			return column, nil
+		}
+	}
+	return "", fmt.Errorf("unknown node-infos csv column: %q", name)
+}
+
+// setField sets the field with the given csv column name from its string value.
+func (n *NodeInfo) setField(column, value string) error {
+	switch column {
+	case "Role":
+		n.Role = decodeRole(value)
+	case "Address":
+		n.Address = value
+	case "NodeID":
+		n.NodeID = value
+	case "Stake", "Weight":
+		if value == "" {
+			return nil
+		}
+		stake, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %q", strings.ToLower(column), value)
+		}
+		n.Stake = stake
+	case "NetworkPubKey":
+		n.NetworkPubKey = value
+	case "StakingPubKey":
+		n.StakingPubKey = value
+	case "StakingPoP":
+		n.StakingPoP = value
+	}
+	return nil
+}
+
+// Write writes the node infos in the given format, with stakes in a Stake field.
+func (l IdentityList) Write(w io.Writer, format Format) error {
+	return Codec{Format: format}.Write(w, l)
+}
+
+// WriteJSON writes the node infos in flow-go's node-infos.pub.json format.
+func (l IdentityList) WriteJSON(w io.Writer) error {
+	return l.writeJSON(w, StakeFieldStake)
+}
+
+// WriteCSV writes the node infos as csv, with a header row.
+func (l IdentityList) WriteCSV(w io.Writer) error {
+	return l.writeCSV(w, StakeFieldStake)
+}
+
+// WriteYAML writes the node infos as a yaml list.
+func (l IdentityList) WriteYAML(w io.Writer) error {
+	return l.writeYAML(w, StakeFieldStake)
+}
+
+// WriteMarkdown writes the node infos as a markdown table.
+func (l IdentityList) WriteMarkdown(w io.Writer) error {
+	return l.writeMarkdown(w, StakeFieldStake)
+}
+
+// Write writes the node infos in the codec's format, with stakes in the codec's stake field.
+func (c Codec) Write(w io.Writer, l IdentityList) error {
+	stakeField := c.StakeField
+	if stakeField == "" {
+		stakeField = StakeFieldStake
+	}
+
+	switch c.Format {
+	case FormatJSON:
+		return l.writeJSON(w, stakeField)
+	case FormatCSV:
+		return l.writeCSV(w, stakeField)
+	case FormatYAML:
+		return l.writeYAML(w, stakeField)
+	case FormatMarkdown:
+		return l.writeMarkdown(w, stakeField)
+	}
+	return fmt.Errorf("invalid format: %s", c.Format)
+}
+
+func (l IdentityList) writeJSON(w io.Writer, stakeField StakeField) error {
+	data, err := json.MarshalIndent(l.fields(stakeField), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling node-infos json: %w", err)
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func (l IdentityList) writeCSV(w io.Writer, stakeField StakeField) error {
+	writer := csv.NewWriter(w)
+
+	header := append([]string{}, csvHeader...)
+	header[3] = string(stakeField)
+
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range l {
+		err := writer.Write([]string{
+			n.Role.String(),
+			n.Address,
+			n.NodeID,
+			strconv.FormatUint(n.Stake, 10),
+			n.NetworkPubKey,
+			n.StakingPubKey,
+			n.StakingPoP,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (l IdentityList) writeYAML(w io.Writer, stakeField StakeField) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	err := encoder.Encode(l.fields(stakeField))
+	if err != nil {
+		return fmt.Errorf("error marshalling node-infos yaml: %w", err)
+	}
+	return encoder.Close()
+}
+
+func (l IdentityList) writeMarkdown(w io.Writer, stakeField StakeField) error {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "| Role | Node ID | Address | %s | Network Key | Staking Key |\n", stakeField)
+	buf.WriteString("|------|---------|---------|------:|-------------|-------------|\n")
+	for _, n := range l {
+		fmt.Fprintf(&buf, "| %s | %s | %s | %d | %s | %s |\n",
+			markdownCell(n.Role.String()),
+			markdownCell(n.NodeID),
+			markdownCell(n.Address),
+			n.Stake,
+			markdownCell(n.NetworkPubKey),
+			markdownCell(n.StakingPubKey),
+		)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func markdownCell(value string) string {
+	return strings.ReplaceAll(value, "|", `\|`)
+}
+
+// Save writes the node infos to a file. The format is chosen from the file extension.
+func (l IdentityList) Save(path string) error {
+	var buf bytes.Buffer
+	err := l.Write(&buf, FormatFromPath(path))
+	if err != nil {
+		return err
+	}
+	return internal.WriteFile(path, buf.Bytes())
+}
@@ -0,0 +1,145 @@
+package identities
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func nodeInfoJSON(stakeField string) string {
+	node := func(role, id, address, stake string) string {
+		return `  {
+    "Role": "` + role + `",
+    "Address": "` + address + `",
+    "NodeID": "` + strings.Repeat(id, nodeIDLen) + `",
+    "` + stakeField + `": ` + stake + `,
+    "NetworkPubKey": "` + strings.Repeat("ab", networkPubKeyLen) + `",
+    "StakingPubKey": "` + strings.Repeat("cd", stakingPubKeyLen) + `"
+  }`
+	}
+	return "[\n" +
+		node("collection", "01", "collection-1.example.com:3569", "1000") + ",\n" +
+		node("access", "02", "access-1.example.com:3569", "0") + ",\n" +
+		node("consensus", "03", "consensus-1.example.com:3569", "18446744073709551615") +
+		"\n]\n"
+}
+
+func writeNodeInfo(t *testing.T, list IdentityList, codec Codec) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := codec.Write(&buf, list); err != nil {
+		t.Fatalf("error writing %s: %v", codec.Format, err)
+	}
+	return buf.String()
+}
+
+func readNodeInfo(t *testing.T, data string, format Format) (IdentityList, Codec) {
+	t.Helper()
+
+	codec := Codec{Format: format}
+	list, err := codec.Read(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("error reading %s: %v", format, err)
+	}
+	return list, codec
+}
+
+func TestNodeInfoRoundTrip(t *testing.T) {
+	for _, stakeField := range []StakeField{StakeFieldStake, StakeFieldWeight} {
+		original := nodeInfoJSON(string(stakeField))
+		list, codec := readNodeInfo(t, original, FormatJSON)
+
+		if list[0].Stake != 1000 || list[2].Stake != 18446744073709551615 {
+			t.Fatalf("%s: stakes were not read: %+v", stakeField, list)
+		}
+		if codec.StakeField != stakeField {
+			t.Fatalf("%s: expected the codec to record the %s field, got %q", stakeField, stakeField, codec.StakeField)
+		}
+
+		if got := writeNodeInfo(t, list, codec); got != original {
+			t.Errorf("%s: json round trip changed the file:\n%s", stakeField, got)
+		}
+
+		for _, format := range []Format{FormatCSV, FormatYAML} {
+			converted := writeNodeInfo(t, list, Codec{Format: format, StakeField: codec.StakeField})
+			if !strings.Contains(converted, string(stakeField)) {
+				t.Errorf("%s: %s output does not use the %s field:\n%s", stakeField, format, stakeField, converted)
+			}
+
+			back, backCodec := readNodeInfo(t, converted, format)
+			backCodec.Format = FormatJSON
+			if got := writeNodeInfo(t, back, backCodec); got != original {
+				t.Errorf("%s: json -> %s -> json changed the file:\n%s", stakeField, format, got)
+			}
+		}
+
+		markdown := writeNodeInfo(t, list, Codec{Format: FormatMarkdown, StakeField: codec.StakeField})
+		if !strings.Contains(markdown, "| "+string(stakeField)+" |") {
+			t.Errorf("%s: markdown header does not use the %s field:\n%s", stakeField, stakeField, markdown)
+		}
+	}
+}
+
+func TestStakeFieldIsNotStoredOnNodes(t *testing.T) {
+	stake, _ := readNodeInfo(t, nodeInfoJSON("Stake"), FormatJSON)
+	weight, _ := readNodeInfo(t, nodeInfoJSON("Weight"), FormatJSON)
+
+	if !reflect.DeepEqual(stake, weight) {
+		t.Errorf("expected nodes read from Stake and Weight fields to be equal:\n%+v\n%+v", stake, weight)
+	}
+
+	// lists written without a codec use the Stake field, like a single node
+	var buf bytes.Buffer
+	if err := weight.Write(&buf, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != nodeInfoJSON("Stake") {
+		t.Errorf("expected the list to be written with a Stake field:\n%s", buf.String())
+	}
+
+	single, err := json.Marshal(weight[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(single), `"Stake":1000`) {
+		t.Errorf("expected a single node to be written with a Stake field: %s", single)
+	}
+}
+
+func TestReadCSVWeightColumn(t *testing.T) {
+	data := "\ufeffrole, node_id ,WEIGHT\naccess," + strings.Repeat("01", nodeIDLen) + ",25\n"
+	list, codec := readNodeInfo(t, data, FormatCSV)
+
+	if len(list) != 1 || list[0].Stake != 25 || list[0].Role != RoleAccess {
+		t.Fatalf("unexpected nodes: %+v", list)
+	}
+	if !strings.HasPrefix(writeNodeInfo(t, list, codec), "Role,Address,NodeID,Weight,") {
+		t.Error("csv written from a weight column should use a Weight column")
+	}
+
+	_, err := ReadNodeInfo(strings.NewReader("Role,Colour\naccess,red\n"), FormatCSV)
+	if err == nil || !strings.Contains(err.Error(), `unknown node-infos csv column: "Colour"`) {
+		t.Errorf("expected an unknown column error, got %v", err)
+	}
+}
+
+func TestParseStakeField(t *testing.T) {
+	for name, want := range map[string]StakeField{"stake": StakeFieldStake, "WEIGHT": StakeFieldWeight} {
+		got, err := ParseStakeField(name)
+		if err != nil || got != want {
+			t.Errorf("%s: expected %s, got %s (%v)", name, want, got, err)
+		}
+	}
+	if _, err := ParseStakeField("amount"); err == nil {
+		t.Error("expected an error for an unknown stake field")
+	}
+}
+
+func TestWriteJSONEmptyList(t *testing.T) {
+	if got := writeNodeInfo(t, nil, Codec{Format: FormatJSON}); got != "[]\n" {
+		t.Errorf("expected an empty json list, got %q", got)
+	}
+}
@@ -1,48 +1,86 @@
 package identities
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+)
 
 type IdentityList []NodeInfo
 
+// ByNodeID returns the node with the given ID. The returned pointer refers to the list element.
 func (l IdentityList) ByNodeID(nodeID string) *NodeInfo {
-	for _, i := range l {
-		if i.NodeID == nodeID {
-			return &i
+	for i := range l {
+		if l[i].NodeID == nodeID {
+			return &l[i]
 		}
 	}
 	return nil
 }
 
+// ByAddress returns the node with the given address. For convenience, the address may also be just
+// the host part of the node's address. IPv6 addresses may be bracketed, and DNS names are matched
+// case-insensitively. The returned pointer refers to the list element.
 func (l IdentityList) ByAddress(address string) *NodeInfo {
-	for _, i := range l {
-		if i.Address == address {
-			return &i
+	for i := range l {
+		if l[i].Address == address {
+			return &l[i]
 		}
+	}
 
-		// for convenience, allow searching by just the host part of an IPv4/DNS address
-		parts := strings.Split(i.Address, ":")
-		if len(parts) == 2 && address == parts[0] {
-			return &i
+	for i := range l {
+		if matchAddress(l[i].Address, address) {
+			return &l[i]
 		}
 	}
 	return nil
 }
 
+// ByNetworkPubKey returns the node with the given network key. The returned pointer refers to the
+// list element.
 func (l IdentityList) ByNetworkPubKey(key string) *NodeInfo {
-	for _, i := range l {
-		if i.NetworkPubKey == key {
-			return &i
+	for i := range l {
+		if l[i].NetworkPubKey == key {
+			return &l[i]
 		}
 	}
 	return nil
 }
 
-func (l IdentityList) ByRole(role string) IdentityList {
-	var result IdentityList
+// ByRole returns the nodes with the given role. The role is matched case-insensitively.
+func (l IdentityList) ByRole(role Role) IdentityList {
+	return l.Filter(HasRole(role))
+}
+
+// Validate checks that every node in the list is well-formed and that node IDs, addresses and keys
+// are unique. It returns all problems found.
+func (l IdentityList) Validate() error {
+	var errs []error
+
+	nodeIDs := make(map[string]bool, len(l))
+	addresses := make(map[string]bool, len(l))
+	networkKeys := make(map[string]bool, len(l))
+	stakingKeys := make(map[string]bool, len(l))
+
+	checkUnique := func(seen map[string]bool, field, value string) {
+		if value == "" {
+			return
+		}
+		if seen[value] {
+			errs = append(errs, fmt.Errorf("duplicate %s: %s", field, value))
+		}
+		seen[value] = true
+	}
+
 	for _, i := range l {
-		if i.Role == role {
-			result = append(result, i)
+		if err := i.Validate(); err != nil {
+			errs = append(errs, err)
 		}
+
+		checkUnique(nodeIDs, "node ID", i.NodeID)
+		checkUnique(addresses, "address", i.Address)
+		checkUnique(networkKeys, "network public key", i.NetworkPubKey)
+		checkUnique(stakingKeys, "staking public key", i.StakingPubKey)
 	}
-	return result
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,94 @@
+package identities
+
+// IdentityIndex provides constant time lookups into an IdentityList. Pointers returned by the index
+// refer to the entries of the indexed list, so changes made through them are visible in the list.
+// The index is not updated if the indexed fields of an entry are changed.
+type IdentityIndex struct {
+	list         IdentityList
+	byNodeID     map[string]*NodeInfo
+	byAddress    map[string]*NodeInfo
+	byHost       map[string]*NodeInfo
+	byNetworkKey map[string]*NodeInfo
+	byStakingKey map[string]*NodeInfo
+	byPeerID     map[string]*NodeInfo
+}
+
+// NewIdentityIndex builds an index over the list. If multiple entries share a value, lookups
+// return the first entry.
+func NewIdentityIndex(list IdentityList) *IdentityIndex {
+	index := &IdentityIndex{
+		list:         list,
+		byNodeID:     make(map[string]*NodeInfo, len(list)),
+		byAddress:    make(map[string]*NodeInfo, len(list)),
+		byHost:       make(map[string]*NodeInfo, len(list)),
+		byNetworkKey: make(map[string]*NodeInfo, len(list)),
+		byStakingKey: make(map[string]*NodeInfo, len(list)),
+		byPeerID:     make(map[string]*NodeInfo, len(list)),
+	}
+
+	for i := range list {
+		node := &list[i]
+
+		addIndex(index.byNodeID, node.NodeID, node)
+		addIndex(index.byAddress, normalizeAddress(node.Address), node)
+		addIndex(index.byNetworkKey, node.NetworkPubKey, node)
+		addIndex(index.byStakingKey, node.StakingPubKey, node)
+
+		host, _ := splitAddress(node.Address)
+		addIndex(index.byHost, host, node)
+
+		if peerID, err := node.PeerID(); err == nil {
+			addIndex(index.byPeerID, peerID, node)
+		}
+	}
+
+	return index
+}
+
+func addIndex(index map[string]*NodeInfo, key string, node *NodeInfo) {
+	if key == "" {
+		return
+	}
+	if _, ok := index[key]; !ok {
+		index[key] = node
+	}
+}
+
+// List returns the indexed list.
+func (x *IdentityIndex) List() IdentityList {
+	return x.list
+}
+
+// ByNodeID returns the node with the given ID.
+func (x *IdentityIndex) ByNodeID(nodeID string) *NodeInfo {
+	return x.byNodeID[nodeID]
+}
+
+// ByAddress returns the node with the given address. Like IdentityList.ByAddress, the address may
+// also be just the host part of the node's address.
+func (x *IdentityIndex) ByAddress(address string) *NodeInfo {
+	if node, ok := x.byAddress[normalizeAddress(address)]; ok {
+		return node
+	}
+	return x.ByHost(address)
+}
+
+// ByHost returns the node with the given host, ignoring the port.
+func (x *IdentityIndex) ByHost(host string) *NodeInfo {
+	return x.byHost[normalizeHost(host)]
+}
+
+// ByNetworkPubKey returns the node with the given network public key.
+func (x *IdentityIndex) ByNetworkPubKey(key string) *NodeInfo {
+	return x.byNetworkKey[key]
+}
+
+// ByStakingPubKey returns the node with the given staking public key.
+func (x *IdentityIndex) ByStakingPubKey(key string) *NodeInfo {
+	return x.byStakingKey[key]
+}
+
+// ByPeerID returns the node with the given libp2p peer ID.
+func (x *IdentityIndex) ByPeerID(peerID string) *NodeInfo {
+	return x.byPeerID[peerID]
+}
@@ -0,0 +1,88 @@
+package identities
+
+import (
+	"testing"
+)
+
+func indexTestList() IdentityList {
+	return IdentityList{
+		{Role: RoleAccess, NodeID: "a1", Address: "access-001.mainnet.nodes.onflow.org:3569", NetworkPubKey: generatorKey, StakingPubKey: "s1"},
+		{Role: RoleAccess, NodeID: "a2", Address: "Access-002.mainnet.nodes.onflow.org.:3569", NetworkPubKey: negatedKey, StakingPubKey: "s2"},
+		{Role: RoleExecution, NodeID: "e1", Address: "[2001:db8::1]:3569", NetworkPubKey: "invalid", StakingPubKey: "s3"},
+		// duplicates of a1's address and staking key
+		{Role: RoleConsensus, NodeID: "c1", Address: "access-001.mainnet.nodes.onflow.org:3569", StakingPubKey: "s1"},
+	}
+}
+
+func TestIdentityIndex(t *testing.T) {
+	list := indexTestList()
+	index := NewIdentityIndex(list)
+
+	tests := []struct {
+		name   string
+		lookup func() *NodeInfo
+		want   int
+	}{
+		{name: "node ID", lookup: func() *NodeInfo { return index.ByNodeID("e1") }, want: 2},
+		{name: "address", lookup: func() *NodeInfo { return index.ByAddress("access-002.mainnet.nodes.onflow.org:3569") }, want: 1},
+		{name: "address without port", lookup: func() *NodeInfo { return index.ByAddress("ACCESS-002.mainnet.nodes.onflow.org") }, want: 1},
+		{name: "unbracketed IPv6 host", lookup: func() *NodeInfo { return index.ByAddress("2001:db8:0::1") }, want: 2},
+		{name: "host", lookup: func() *NodeInfo { return index.ByHost("[2001:db8::1]") }, want: 2},
+		{name: "network key", lookup: func() *NodeInfo { return index.ByNetworkPubKey(negatedKey) }, want: 1},
+		{name: "staking key", lookup: func() *NodeInfo { return index.ByStakingPubKey("s3") }, want: 2},
+		{name: "peer ID", lookup: func() *NodeInfo { return index.ByPeerID(generatorPeerID) }, want: 0},
+		{name: "duplicate address", lookup: func() *NodeInfo { return index.ByAddress("access-001.mainnet.nodes.onflow.org:3569") }, want: 0},
+		{name: "duplicate staking key", lookup: func() *NodeInfo { return index.ByStakingPubKey("s1") }, want: 0},
+		{name: "unknown node ID", lookup: func() *NodeInfo { return index.ByNodeID("x") }, want: -1},
+		{name: "unknown port", lookup: func() *NodeInfo { return index.ByAddress("[2001:db8::1]:9000") }, want: -1},
+		{name: "empty key", lookup: func() *NodeInfo { return index.ByNetworkPubKey("") }, want: -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.lookup()
+			if test.want < 0 {
+				if got != nil {
+					t.Fatalf("expected no node, got %+v", got)
+				}
+				return
+			}
+			if got != &list[test.want] {
+				t.Fatalf("expected a pointer to node %s, got %+v", list[test.want].NodeID, got)
+			}
+		})
+	}
+}
+
+func TestIdentityIndexMatchesList(t *testing.T) {
+	list := indexTestList()
+	index := NewIdentityIndex(list)
+
+	for _, node := range list {
+		if got, want := index.ByNodeID(node.NodeID), list.ByNodeID(node.NodeID); got != want {
+			t.Errorf("%s: node ID lookup differs from the list", node.NodeID)
+		}
+		if got, want := index.ByAddress(node.Address), list.ByAddress(node.Address); got != want {
+			t.Errorf("%s: address lookup differs from the list", node.NodeID)
+		}
+		if got, want := index.ByNetworkPubKey(node.NetworkPubKey), list.ByNetworkPubKey(node.NetworkPubKey); node.NetworkPubKey != "" && got != want {
+			t.Errorf("%s: network key lookup differs from the list", node.NodeID)
+		}
+		if peerID, err := node.PeerID(); err == nil && index.ByPeerID(peerID) != list.ByPeerID(peerID) {
+			t.Errorf("%s: peer ID lookup differs from the list", node.NodeID)
+		}
+	}
+}
+
+func TestIdentityIndexSharesEntries(t *testing.T) {
+	list := indexTestList()
+	index := NewIdentityIndex(list)
+
+	index.ByNodeID("a2").Stake = 500
+	if list[1].Stake != 500 {
+		t.Error("expected the change to be visible in the list")
+	}
+	if index.List()[1].Stake != 500 {
+		t.Error("expected the change to be visible in the indexed list")
+	}
+}
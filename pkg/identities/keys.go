@@ -0,0 +1,98 @@
+package identities
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/onflow/crypto"
+)
+
+// stakingPoPLen is the length of a compressed BLS12-381 signature.
+const stakingPoPLen = 48
+
+// NetworkKey returns the node's ECDSA secp256k1 network public key.
+func (n NodeInfo) NetworkKey() (crypto.PublicKey, error) {
+	return decodePublicKey(crypto.ECDSASecp256k1, n.NetworkPubKey)
+}
+
+// StakingKey returns the node's BLS12-381 staking public key.
+func (n NodeInfo) StakingKey() (crypto.PublicKey, error) {
+	return decodePublicKey(crypto.BLSBLS12381, n.StakingPubKey)
+}
+
+// VerifyKeys checks that the node's public keys decode to valid keys, and verifies the staking
+// key's proof of possession if the node info includes one.
+func (n NodeInfo) VerifyKeys() error {
+	var errs []error
+
+	if _, err := n.NetworkKey(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid network public key: %w", err))
+	}
+
+	stakingKey, err := n.StakingKey()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("invalid staking public key: %w", err))
+	}
+
+	if stakingKey != nil && n.StakingPoP != "" {
+		if err := verifyPoP(stakingKey, n.StakingPoP); err != nil {
+			errs = append(errs, fmt.Errorf("invalid staking key proof of possession: %w", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("node %s: %w", n.NodeID, errors.Join(errs...))
+	}
+	return nil
+}
+
+// VerifyKeys verifies the keys of every node in the list, and returns all problems found.
+func (l IdentityList) VerifyKeys() error {
+	var errs []error
+	for _, i := range l {
+		if err := i.VerifyKeys(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func decodePublicKey(algo crypto.SigningAlgorithm, key string) (crypto.PublicKey, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%q is not valid hex", key)
+	}
+	return crypto.DecodePublicKey(algo, data)
+}
+
+func verifyPoP(key crypto.PublicKey, pop string) error {
+	sig, err := decodeSignature(pop)
+	if err != nil {
+		return err
+	}
+
+	valid, err := crypto.BLSVerifyPOP(key, sig)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("proof of possession does not match the key")
+	}
+	return nil
+}
+
+// decodeSignature decodes a proof of possession, which may be hex or base64 encoded.
+func decodeSignature(sig string) (crypto.Signature, error) {
+	if data, err := hex.DecodeString(strings.TrimPrefix(sig, "0x")); err == nil && len(data) == stakingPoPLen {
+		return data, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil || len(data) != stakingPoPLen {
+		return nil, fmt.Errorf("%q is not a valid signature", sig)
+	}
+	return data, nil
+}
@@ -0,0 +1,187 @@
+package identities
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/onflow/crypto"
+)
+
+// keyedNode returns a node with generated keys and a hex encoded proof of possession.
+func keyedNode(t *testing.T, seed byte) (NodeInfo, crypto.PrivateKey) {
+	t.Helper()
+
+	stakingKey, err := crypto.GeneratePrivateKey(crypto.BLSBLS12381, bytes.Repeat([]byte{seed}, crypto.KeyGenSeedMinLen))
+	if err != nil {
+		t.Fatal(err)
+	}
+	networkKey, err := crypto.GeneratePrivateKey(crypto.ECDSASecp256k1, bytes.Repeat([]byte{seed + 1}, crypto.KeyGenSeedMinLen))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pop, err := crypto.BLSGeneratePOP(stakingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NodeInfo{
+		Role:          RoleConsensus,
+		Address:       "consensus-1.example.com:3569",
+		NodeID:        strings.Repeat(hex.EncodeToString([]byte{seed}), nodeIDLen),
+		Stake:         1000,
+		NetworkPubKey: hex.EncodeToString(networkKey.PublicKey().Encode()),
+		StakingPubKey: hex.EncodeToString(stakingKey.PublicKey().Encode()),
+		StakingPoP:    hex.EncodeToString(pop),
+	}, stakingKey
+}
+
+func TestVerifyKeys(t *testing.T) {
+	other, otherKey := keyedNode(t, 9)
+
+	tests := []struct {
+		name   string
+		modify func(n *NodeInfo)
+		want   []string
+	}{
+		{
+			name:   "hex proof of possession",
+			modify: func(n *NodeInfo) {},
+		},
+		{
+			name: "prefixed keys",
+			modify: func(n *NodeInfo) {
+				n.NetworkPubKey = "0x" + n.NetworkPubKey
+				n.StakingPubKey = "0x" + n.StakingPubKey
+				n.StakingPoP = "0x" + n.StakingPoP
+			},
+		},
+		{
+			name: "base64 proof of possession",
+			modify: func(n *NodeInfo) {
+				pop, _ := hex.DecodeString(n.StakingPoP)
+				n.StakingPoP = base64.StdEncoding.EncodeToString(pop)
+			},
+		},
+		{
+			name:   "no proof of possession",
+			modify: func(n *NodeInfo) { n.StakingPoP = "" },
+		},
+		{
+			name:   "proof of possession of another key",
+			modify: func(n *NodeInfo) { n.StakingPoP = other.StakingPoP },
+			want:   []string{"proof of possession does not match the key"},
+		},
+		{
+			name: "signature that is not a proof of possession",
+			modify: func(n *NodeInfo) {
+				sig, err := otherKey.Sign([]byte("message"), crypto.NewExpandMsgXOFKMAC128("tag"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				n.StakingPoP = hex.EncodeToString(sig)
+			},
+			want: []string{"proof of possession does not match the key"},
+		},
+		{
+			name:   "malformed proof of possession",
+			modify: func(n *NodeInfo) { n.StakingPoP = "abcd" },
+			want:   []string{`"abcd" is not a valid signature`},
+		},
+		{
+			name:   "network key hex",
+			modify: func(n *NodeInfo) { n.NetworkPubKey = "xyz" },
+			want:   []string{"invalid network public key", `"xyz" is not valid hex`},
+		},
+		{
+			name:   "network key length",
+			modify: func(n *NodeInfo) { n.NetworkPubKey = n.NetworkPubKey[:64] },
+			want:   []string{"invalid network public key"},
+		},
+		{
+			name: "staking key not on the curve",
+			modify: func(n *NodeInfo) {
+				n.StakingPubKey = strings.Repeat("ab", stakingPubKeyLen)
+			},
+			want: []string{"invalid staking public key"},
+		},
+		{
+			name: "all problems are reported",
+			modify: func(n *NodeInfo) {
+				n.NetworkPubKey = "xyz"
+				n.StakingPoP = other.StakingPoP
+			},
+			want: []string{"invalid network public key", "proof of possession does not match the key"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node, _ := keyedNode(t, 1)
+			test.modify(&node)
+
+			err := node.VerifyKeys()
+			if len(test.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected an error containing %q", test.want)
+			}
+			if !strings.Contains(err.Error(), "node "+node.NodeID) {
+				t.Errorf("error %q does not name the node", err)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIdentityListVerifyKeys(t *testing.T) {
+	valid, _ := keyedNode(t, 1)
+	invalid, _ := keyedNode(t, 3)
+	invalid.StakingPoP = valid.StakingPoP
+
+	if err := (IdentityList{valid}).VerifyKeys(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := IdentityList{invalid, valid, invalid}.VerifyKeys()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if count := strings.Count(err.Error(), "node "+invalid.NodeID); count != 2 {
+		t.Errorf("expected both invalid nodes to be reported, got %q", err)
+	}
+	if strings.Contains(err.Error(), valid.NodeID) {
+		t.Errorf("expected the valid node not to be reported, got %q", err)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	node, stakingKey := keyedNode(t, 1)
+
+	key, err := node.StakingKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !key.Equals(stakingKey.PublicKey()) {
+		t.Error("staking key does not match the generated key")
+	}
+
+	networkKey, err := node.NetworkKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if networkKey.Algorithm() != crypto.ECDSASecp256k1 {
+		t.Errorf("expected a secp256k1 network key, got %s", networkKey.Algorithm())
+	}
+}
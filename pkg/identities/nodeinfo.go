@@ -1,24 +1,43 @@
 package identities
 
 import (
-	"encoding/json"
+	"bytes"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
+	"strconv"
 	"strings"
 
 	"github.com/peterargue/flow-info/internal"
 )
 
+const (
+	nodeIDLen        = 32
+	networkPubKeyLen = 64
+	stakingPubKeyLen = 96
+)
+
 type NodeInfo struct {
-	Role          string
-	Address       string
-	NodeID        string
-	Stake         uint64
-	NetworkPubKey string
-	StakingPubKey string
+	Role          Role   `yaml:"Role"`
+	Address       string `yaml:"Address"`
+	NodeID        string `yaml:"NodeID"`
+	Stake         uint64 `yaml:"Stake"`
+	NetworkPubKey string `yaml:"NetworkPubKey"`
+	StakingPubKey string `yaml:"StakingPubKey"`
+	StakingPoP    string `json:",omitempty" yaml:"StakingPoP,omitempty"`
 }
 
-// LoadNodeInfo loads node infos from a file or url.
+// LoadNodeInfo loads node infos from a file or url. Files ending in .csv, .yaml or .yml are read
+// in that format, and all others are read as flow-go's node-infos.pub.json format.
 func LoadNodeInfo(url string) (IdentityList, error) {
+	codec := Codec{Format: FormatFromPath(url)}
+	return codec.Load(url)
+}
+
+// Load loads node infos in the codec's format from a file or url, and sets the codec's stake field
+// to the name used by the file.
+func (c *Codec) Load(url string) (IdentityList, error) {
 	var data []byte
 	var err error
 
@@ -34,11 +53,62 @@ func LoadNodeInfo(url string) (IdentityList, error) {
 		}
 	}
 
-	var nodeInfos []NodeInfo
-	err = json.Unmarshal(data, &nodeInfos)
+	return c.Read(bytes.NewReader(data))
+}
+
+// Validate checks that the node info fields are well-formed, and returns all problems found.
+func (n NodeInfo) Validate() error {
+	var errs []error
+
+	if !n.Role.Valid() {
+		errs = append(errs, fmt.Errorf("invalid role: %q", n.Role))
+	}
+
+	if err := validateHex(n.NodeID, nodeIDLen); err != nil {
+		errs = append(errs, fmt.Errorf("invalid node ID: %w", err))
+	}
+
+	if err := validateAddress(n.Address); err != nil {
+		errs = append(errs, fmt.Errorf("invalid address: %w", err))
+	}
+
+	if err := validateHex(n.NetworkPubKey, networkPubKeyLen); err != nil {
+		errs = append(errs, fmt.Errorf("invalid network public key: %w", err))
+	}
+
+	if err := validateHex(n.StakingPubKey, stakingPubKeyLen); err != nil {
+		errs = append(errs, fmt.Errorf("invalid staking public key: %w", err))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("node %s: %w", n.NodeID, errors.Join(errs...))
+	}
+	return nil
+}
+
+func validateHex(value string, length int) error {
+	data, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling node-infos json: %w", err)
+		return fmt.Errorf("%q is not valid hex", value)
+	}
+	if len(data) != length {
+		return fmt.Errorf("expected %d bytes, got %d", length, len(data))
+	}
+	return nil
+}
+
+func validateAddress(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return fmt.Errorf("%q is missing a host", address)
 	}
 
-	return nodeInfos, nil
+	portNum, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || portNum == 0 {
+		return fmt.Errorf("%q has an invalid port", address)
+	}
+	return nil
 }
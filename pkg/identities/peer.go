@@ -0,0 +1,86 @@
+package identities
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/peterargue/flow-info/internal"
+)
+
+// PeerID returns the libp2p peer ID derived from the node's network public key.
+func (n NodeInfo) PeerID() (string, error) {
+	return PeerIDFromNetworkKey(n.NetworkPubKey)
+}
+
+// Multiaddr returns the libp2p multiaddr used to dial the node, including its peer ID.
+func (n NodeInfo) Multiaddr() (string, error) {
+	peerID, err := n.PeerID()
+	if err != nil {
+		return "", err
+	}
+	return Multiaddr(n.Address, peerID)
+}
+
+// ByPeerID returns the node with the libp2p peer ID. The returned pointer refers to the list element.
+func (l IdentityList) ByPeerID(peerID string) *NodeInfo {
+	for i := range l {
+		id, err := l[i].PeerID()
+		if err == nil && id == peerID {
+			return &l[i]
+		}
+	}
+	return nil
+}
+
+// PeerIDFromNetworkKey derives the libp2p peer ID from a hex encoded secp256k1 network public key.
+// Keys may be either uncompressed (64 bytes, as used by flow) or compressed (33 bytes).
+func PeerIDFromNetworkKey(key string) (string, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+	if err != nil {
+		return "", fmt.Errorf("%q is not valid hex", key)
+	}
+
+	var compressed []byte
+	switch len(data) {
+	case 64:
+		// the compressed form is the X coordinate prefixed by the parity of the Y coordinate
+		prefix := byte(0x02)
+		if data[63]&1 == 1 {
+			prefix = 0x03
+		}
+		compressed = append([]byte{prefix}, data[:32]...)
+	case 33:
+		compressed = data
+	default:
+		return "", fmt.Errorf("invalid network public key length: %d", len(data))
+	}
+
+	// libp2p encodes public keys as a protobuf message with the key type (2 = secp256k1) and data
+	encoded := []byte{0x08, 0x02, 0x12, byte(len(compressed))}
+	encoded = append(encoded, compressed...)
+
+	// keys shorter than 42 bytes are embedded in the peer ID using the identity multihash
+	multihash := append([]byte{0x00, byte(len(encoded))}, encoded...)
+
+	return internal.Base58Encode(multihash), nil
+}
+
+// Multiaddr returns the libp2p multiaddr for a host:port address and peer ID.
+func Multiaddr(address, peerID string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %s: %w", address, err)
+	}
+
+	protocol := "dns4"
+	if ip := net.ParseIP(host); ip != nil {
+		protocol = "ip4"
+		if ip.To4() == nil {
+			protocol = "ip6"
+		}
+	}
+
+	return fmt.Sprintf("/%s/%s/tcp/%s/p2p/%s", protocol, host, port, peerID), nil
+}
@@ -0,0 +1,135 @@
+package identities
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	lcrypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/onflow/crypto"
+)
+
+// The secp256k1 generator point G, which has an even Y coordinate, and its negation -G, which has
+// an odd one, as uncompressed 64 byte keys. Their peer IDs were derived with go-libp2p.
+const (
+	generatorKey    = "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"
+	generatorPeerID = "16Uiu2HAm3cuhhRL2msUuLF62KRSfneFDx94RsuouyW25Ho42cFMq"
+	negatedKey      = "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798b7c52588d95c3b9aa25b0403f1eef75702e84bb7597aabe663b82f6f04ef2777"
+	negatedPeerID   = "16Uiu2HAmLrE5CD5dZvDfuKsSYbcxys3kCdhkd7t1TYyQ9iUo8Cc7"
+)
+
+func TestPeerIDFromNetworkKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{name: "even Y", key: generatorKey, want: generatorPeerID},
+		{name: "odd Y", key: negatedKey, want: negatedPeerID},
+		{name: "prefixed", key: "0x" + generatorKey, want: generatorPeerID},
+		{name: "compressed even Y", key: "02" + generatorKey[:64], want: generatorPeerID},
+		{name: "compressed odd Y", key: "03" + negatedKey[:64], want: negatedPeerID},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := PeerIDFromNetworkKey(test.key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("expected %s, got %s", test.want, got)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"xyz", "abcd", strings.Repeat("ab", 65)} {
+		if _, err := PeerIDFromNetworkKey(invalid); err == nil {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+}
+
+func TestPeerIDMatchesLibp2p(t *testing.T) {
+	for i := byte(0); i < 20; i++ {
+		key, err := crypto.GeneratePrivateKey(crypto.ECDSASecp256k1, bytes.Repeat([]byte{i}, crypto.KeyGenSeedMinLen))
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded := key.PublicKey().Encode()
+
+		// libp2p expects the uncompressed key with its 0x04 prefix
+		libp2pKey, err := lcrypto.UnmarshalSecp256k1PublicKey(append([]byte{0x04}, encoded...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := peer.IDFromPublicKey(libp2pKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := PeerIDFromNetworkKey(hex.EncodeToString(encoded))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want.String() {
+			t.Errorf("key %x: expected %s, got %s", encoded, want, got)
+		}
+	}
+}
+
+func TestMultiaddr(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{address: "access-1.mainnet.nodes.onflow.org:3569", want: "/dns4/access-1.mainnet.nodes.onflow.org/tcp/3569/p2p/" + generatorPeerID},
+		{address: "192.168.1.10:3569", want: "/ip4/192.168.1.10/tcp/3569/p2p/" + generatorPeerID},
+		{address: "[2001:db8::1]:3569", want: "/ip6/2001:db8::1/tcp/3569/p2p/" + generatorPeerID},
+	}
+
+	for _, test := range tests {
+		got, err := Multiaddr(test.address, generatorPeerID)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.address, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: expected %s, got %s", test.address, test.want, got)
+		}
+	}
+
+	if _, err := Multiaddr("no-port", generatorPeerID); err == nil {
+		t.Error("expected an error for an address without a port")
+	}
+
+	node := NodeInfo{Address: "192.168.1.10:3569", NetworkPubKey: generatorKey}
+	got, err := node.Multiaddr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != tests[1].want {
+		t.Errorf("expected %s, got %s", tests[1].want, got)
+	}
+}
+
+func TestByPeerID(t *testing.T) {
+	list := IdentityList{
+		{NodeID: "a", NetworkPubKey: "invalid"},
+		{NodeID: "b", NetworkPubKey: generatorKey},
+		{NodeID: "c", NetworkPubKey: negatedKey},
+	}
+
+	node := list.ByPeerID(negatedPeerID)
+	if node == nil || node.NodeID != "c" {
+		t.Fatalf("expected node c, got %+v", node)
+	}
+	if node != &list[2] {
+		t.Error("expected a pointer to the list element")
+	}
+	if list.ByPeerID("16Uiu2HAmUnknown") != nil {
+		t.Error("expected no node for an unknown peer ID")
+	}
+}
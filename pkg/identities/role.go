@@ -0,0 +1,86 @@
+package identities
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Role is the role of a node in the flow network.
+type Role string
+
+const (
+	RoleCollection   Role = "collection"
+	RoleConsensus    Role = "consensus"
+	RoleExecution    Role = "execution"
+	RoleVerification Role = "verification"
+	RoleAccess       Role = "access"
+)
+
+// Roles is the list of all roles, in the order of flow-go's numeric role encoding.
+var Roles = []Role{RoleCollection, RoleConsensus, RoleExecution, RoleVerification, RoleAccess}
+
+// ParseRole parses a role from its name. Names are matched case-insensitively.
+func ParseRole(name string) (Role, error) {
+	for _, role := range Roles {
+		if strings.EqualFold(name, string(role)) {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("invalid role: %s", name)
+}
+
+// Valid returns true if the role is one of the flow roles.
+func (r Role) Valid() bool {
+	for _, role := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the name of the role.
+func (r Role) String() string {
+	return string(r)
+}
+
+// UnmarshalJSON decodes a role from either its name or flow-go's numeric encoding (1-5). Unknown
+// roles are kept as-is rather than failing the decode, so Validate can report them per node.
+func (r *Role) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*r = decodeRole(name)
+		return nil
+	}
+
+	var index int
+	if err := json.Unmarshal(data, &index); err != nil {
+		return fmt.Errorf("invalid role: %s", data)
+	}
+
+	*r = decodeRole(strconv.Itoa(index))
+	return nil
+}
+
+// UnmarshalYAML decodes a role from either its name or flow-go's numeric encoding (1-5). Unknown
+// roles are kept as-is rather than failing the decode, so Validate can report them per node.
+func (r *Role) UnmarshalYAML(value *yaml.Node) error {
+	*r = decodeRole(value.Value)
+	return nil
+}
+
+// decodeRole decodes a role from either its name or flow-go's numeric encoding (1-5). Unknown
+// values are returned unchanged as an invalid role.
+func decodeRole(value string) Role {
+	if index, err := strconv.Atoi(value); err == nil && index >= 1 && index <= len(Roles) {
+		return Roles[index-1]
+	}
+	if role, err := ParseRole(value); err == nil {
+		return role
+	}
+	return Role(value)
+}
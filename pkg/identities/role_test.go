@@ -0,0 +1,90 @@
+package identities
+
+import (
+	"strings"
+	"testing"
+)
+
+func testNode(role, nodeID string) string {
+	return `{"Role":` + role + `,"Address":"` + nodeID[:4] + `.example.com:3569","NodeID":"` + nodeID + `","Stake":1000,` +
+		`"NetworkPubKey":"` + strings.Repeat("ab", networkPubKeyLen) + `","StakingPubKey":"` + strings.Repeat("cd", stakingPubKeyLen) + `"}`
+}
+
+func TestUnknownRoleIsReportedByValidate(t *testing.T) {
+	first := strings.Repeat("01", nodeIDLen)
+	second := strings.Repeat("02", nodeIDLen)
+	third := strings.Repeat("03", nodeIDLen)
+	data := "[" + testNode(`"collection"`, first) + "," + testNode(`"observer"`, second) + "," + testNode(`9`, third) + "]"
+
+	list, err := ReadNodeInfo(strings.NewReader(data), FormatJSON)
+	if err != nil {
+		t.Fatalf("an unknown role should not fail the decode: %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("expected 3 nodes, got %d", len(list))
+	}
+	if list[0].Role != RoleCollection {
+		t.Errorf("expected role collection, got %q", list[0].Role)
+	}
+
+	err = list.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors for the unknown roles")
+	}
+	msg := err.Error()
+	for _, want := range []string{"node " + second + `: invalid role: "observer"`, "node " + third + `: invalid role: "9"`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("validation error %q does not contain %q", msg, want)
+		}
+	}
+	if strings.Contains(msg, first) {
+		t.Errorf("valid node reported: %q", msg)
+	}
+}
+
+func TestDecodeRole(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Role
+		valid bool
+	}{
+		{"collection", RoleCollection, true},
+		{"Consensus", RoleConsensus, true},
+		{"3", RoleExecution, true},
+		{"5", RoleAccess, true},
+		{"0", Role("0"), false},
+		{"6", Role("6"), false},
+		{"observer", Role("observer"), false},
+		{"", Role(""), false},
+	}
+
+	for _, test := range tests {
+		role := decodeRole(test.value)
+		if role != test.want {
+			t.Errorf("decodeRole(%q) = %q, expected %q", test.value, role, test.want)
+		}
+		if role.Valid() != test.valid {
+			t.Errorf("decodeRole(%q).Valid() = %v, expected %v", test.value, role.Valid(), test.valid)
+		}
+	}
+}
+
+func TestUnknownRoleInYAMLAndCSV(t *testing.T) {
+	yamlData := "- Role: observer\n  NodeID: \"" + strings.Repeat("01", nodeIDLen) + "\"\n"
+	list, err := ReadNodeInfo(strings.NewReader(yamlData), FormatYAML)
+	if err != nil {
+		t.Fatalf("error reading yaml: %v", err)
+	}
+	if list[0].Role.Valid() {
+		t.Errorf("expected an invalid role, got %q", list[0].Role)
+	}
+
+	csvData := "Role,NodeID\nobserver," + strings.Repeat("01", nodeIDLen) + "\n"
+	list, err = ReadNodeInfo(strings.NewReader(csvData), FormatCSV)
+	if err != nil {
+		t.Fatalf("error reading csv: %v", err)
+	}
+	if list[0].Role != Role("observer") {
+		t.Errorf("expected role observer, got %q", list[0].Role)
+	}
+}
@@ -1,7 +1,11 @@
 package info
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 
 	"github.com/peterargue/flow-info/internal"
 )
@@ -15,3 +19,67 @@ func Save(url, saveTo string) error {
 
 	return internal.WriteFile(saveTo, data)
 }
+
+// SaveArtefact downloads a spork artefact and saves it to a file. The data is streamed to disk, so
+// large archives are never held in memory, and the file is only created once the download has
+// completed. It returns the number of bytes written.
+//
+// If checksum is not empty, it is the hex encoded sha256 of the artefact, or the url of a checksum
+// file, in the formats accepted by archive.Extract. The data is hashed while it is written, and
+// the file is not created if the checksum does not match.
+func SaveArtefact(url, checksum, saveTo string) (int64, error) {
+	checksum, err := internal.ResolveChecksum(checksum)
+	if err != nil {
+		return 0, err
+	}
+
+	var expected []byte
+	if checksum != "" {
+		expected, err = internal.ParseChecksum(checksum)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	r, err := internal.OpenImmutable(url, expected)
+	if err != nil {
+		return 0, fmt.Errorf("error downloading data: %w", err)
+	}
+	defer r.Close()
+
+	file, err := os.CreateTemp(filepath.Dir(saveTo), filepath.Base(saveTo)+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("error creating file (path=%s): %w", saveTo, err)
+	}
+	defer os.Remove(file.Name())
+
+	// temp files are only readable by the owner, so use the same permissions as os.Create
+	err = file.Chmod(0644)
+	if err != nil {
+		file.Close()
+		return 0, fmt.Errorf("error creating file (path=%s): %w", saveTo, err)
+	}
+
+	hasher := sha256.New()
+	written, err := io.Copy(io.MultiWriter(file, hasher), r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error downloading data: %w", err)
+	}
+
+	if expected != nil {
+		err = internal.VerifyChecksum(hasher, expected)
+		if err != nil {
+			return 0, fmt.Errorf("error verifying %s: %w", url, err)
+		}
+	}
+
+	err = os.Rename(file.Name(), saveTo)
+	if err != nil {
+		return 0, fmt.Errorf("error writing file (path=%s): %w", saveTo, err)
+	}
+
+	return written, nil
+}
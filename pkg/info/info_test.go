@@ -0,0 +1,111 @@
+package info
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/peterargue/flow-info/pkg/cache"
+)
+
+const artefact = "root checkpoint data"
+
+func artefactServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	sum := sha256.Sum256([]byte(artefact))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/root.checkpoint", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(artefact))
+	})
+	mux.HandleFunc("/root.checkpoint.sha256", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(hex.EncodeToString(sum[:]) + "  root.checkpoint\n"))
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestSaveArtefact(t *testing.T) {
+	ts := artefactServer(t)
+	sum := sha256.Sum256([]byte(artefact))
+	wrong := strings.Repeat("00", sha256.Size)
+
+	tests := []struct {
+		name     string
+		checksum string
+		err      string
+	}{
+		{name: "no checksum"},
+		{name: "checksum", checksum: hex.EncodeToString(sum[:])},
+		{name: "prefixed checksum", checksum: "sha256:" + hex.EncodeToString(sum[:])},
+		{name: "checksum with file name", checksum: hex.EncodeToString(sum[:]) + "  root.checkpoint"},
+		{name: "checksum url", checksum: ts.URL + "/root.checkpoint.sha256"},
+		{name: "mismatch", checksum: wrong, err: "checksum mismatch"},
+		{name: "invalid checksum", checksum: "abc", err: "invalid sha256 checksum"},
+		{name: "missing checksum file", checksum: ts.URL + "/missing.sha256", err: "error downloading checksum"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "root.checkpoint")
+
+			written, err := SaveArtefact(ts.URL+"/root.checkpoint", test.checksum, path)
+
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing %q, got %v", test.err, err)
+				}
+				// neither the file nor the temporary file are left behind
+				entries, _ := os.ReadDir(dir)
+				if len(entries) != 0 {
+					t.Fatalf("expected no files, got %v", entries)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if written != int64(len(artefact)) {
+				t.Errorf("expected %d bytes written, got %d", len(artefact), written)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != artefact {
+				t.Errorf("expected %q, got %q", artefact, data)
+			}
+		})
+	}
+}
+
+func TestSaveArtefactCached(t *testing.T) {
+	ts := artefactServer(t)
+
+	cache.SetDefault(cache.New(t.TempDir()))
+	defer cache.SetDefault(nil)
+
+	// the cached file is verified as well, not only used as the cache key
+	path := filepath.Join(t.TempDir(), "root.checkpoint")
+	_, err := SaveArtefact(ts.URL+"/root.checkpoint", strings.Repeat("00", sha256.Size), path)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the file not to be created, got %v", err)
+	}
+
+	_, err = SaveArtefact(ts.URL+"/root.checkpoint", ts.URL+"/root.checkpoint.sha256", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
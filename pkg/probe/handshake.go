@@ -0,0 +1,45 @@
+package probe
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/security/noise"
+	mss "github.com/multiformats/go-multistream"
+)
+
+// handshake negotiates the noise security protocol on the connection and runs libp2p's noise
+// handshake with an ephemeral identity. The handshake fails unless the remote peer authenticates
+// as the expected peer ID. The connection must not be used afterwards.
+func handshake(ctx context.Context, conn net.Conn, expected string) (string, error) {
+	remote, err := peer.Decode(expected)
+	if err != nil {
+		return "", fmt.Errorf("error decoding peer ID: %w", err)
+	}
+
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("error generating identity key: %w", err)
+	}
+
+	transport, err := noise.New(noise.ID, key, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating noise transport: %w", err)
+	}
+
+	err = mss.SelectProtoOrFail(noise.ID, conn)
+	if err != nil {
+		return "", fmt.Errorf("error negotiating security protocol: %w", err)
+	}
+
+	secure, err := transport.SecureOutbound(ctx, conn, remote)
+	if err != nil {
+		return "", fmt.Errorf("error during noise handshake: %w", err)
+	}
+
+	return secure.RemotePeer().String(), nil
+}
@@ -0,0 +1,146 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+)
+
+const (
+	DefaultConcurrency = 32
+	DefaultTimeout     = 5 * time.Second
+)
+
+// Prober checks the network connectivity of nodes.
+type Prober struct {
+	// Concurrency is the maximum number of nodes probed at once.
+	Concurrency int
+
+	// Timeout is the maximum time to connect to, and handshake with, a single node.
+	Timeout time.Duration
+
+	// Handshake enables a libp2p handshake after connecting, which checks that the node presents
+	// the identity of its network public key.
+	Handshake bool
+
+	// DialContext opens tcp connections to nodes. If nil, a net.Dialer is used.
+	DialContext func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// NewProber returns a prober with the default concurrency and timeout.
+func NewProber() *Prober {
+	return &Prober{
+		Concurrency: DefaultConcurrency,
+		Timeout:     DefaultTimeout,
+	}
+}
+
+// Result is the outcome of probing a node.
+type Result struct {
+	Node identities.NodeInfo
+
+	// Reachable is true if a tcp connection was opened to the node.
+	Reachable bool
+
+	// Latency is the time taken to open the tcp connection.
+	Latency time.Duration
+
+	// HandshakeLatency is the time taken by the libp2p handshake, if one was run.
+	HandshakeLatency time.Duration
+
+	// PeerID is the libp2p peer ID the node authenticated as during the handshake. It is only set
+	// if the peer ID matches the node's network key.
+	PeerID string
+
+	// Err is the reason the probe failed, or nil if it succeeded.
+	Err error
+}
+
+// Probe probes all nodes in the list, and returns the results in list order.
+func (p *Prober) Probe(ctx context.Context, list identities.IdentityList) Results {
+	concurrency := p.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	results := make(Results, len(list))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, node := range list {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = p.ProbeNode(ctx, node)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// ProbeNode probes a single node.
+func (p *Prober) ProbeNode(ctx context.Context, node identities.NodeInfo) Result {
+	result := Result{Node: node}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dial := p.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	start := time.Now()
+	conn, err := dial(ctx, "tcp", node.Address)
+	if err != nil {
+		result.Err = fmt.Errorf("error connecting to %s: %w", node.Address, err)
+		return result
+	}
+	defer conn.Close()
+
+	result.Reachable = true
+	result.Latency = time.Since(start)
+
+	if !p.Handshake {
+		return result
+	}
+
+	expected, err := node.PeerID()
+	if err != nil {
+		result.Err = fmt.Errorf("error getting peer ID: %w", err)
+		return result
+	}
+
+	// stop the handshake when the context is done, since reads and writes are not context aware
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	start = time.Now()
+	peerID, err := handshake(ctx, conn, expected)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.HandshakeLatency = time.Since(start)
+	result.PeerID = peerID
+
+	return result
+}
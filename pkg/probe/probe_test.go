@@ -0,0 +1,249 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/security/noise"
+	mss "github.com/multiformats/go-multistream"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+)
+
+// responder is a local libp2p peer that accepts the noise security protocol, using go-libp2p's
+// noise transport.
+type responder struct {
+	listener net.Listener
+	peerID   string
+	// networkKey is the hex encoded uncompressed public key, in the format used by node infos
+	networkKey string
+}
+
+func newResponder(t *testing.T) *responder {
+	t.Helper()
+
+	priv, pub, err := crypto.GenerateSecp256k1Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := pub.Raw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := secp256k1.ParsePubKey(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport, err := noise.New(noise.ID, priv, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+
+				muxer := mss.NewMultistreamMuxer[string]()
+				muxer.AddHandler("/noise", nil)
+				if _, _, err := muxer.Negotiate(conn); err != nil {
+					return
+				}
+				_, _ = transport.SecureInbound(context.Background(), conn, "")
+			}()
+		}
+	}()
+
+	return &responder{
+		listener:   listener,
+		peerID:     id.String(),
+		networkKey: hex.EncodeToString(key.SerializeUncompressed()[1:]),
+	}
+}
+
+func (r *responder) node() identities.NodeInfo {
+	return identities.NodeInfo{
+		Role:          identities.RoleAccess,
+		NodeID:        strings.Repeat("01", 32),
+		Address:       r.listener.Addr().String(),
+		NetworkPubKey: r.networkKey,
+	}
+}
+
+// listen starts a local listener that runs serve for each connection.
+func listen(t *testing.T, serve func(conn net.Conn)) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestProbeHandshake(t *testing.T) {
+	r := newResponder(t)
+
+	prober := &Prober{Timeout: 5 * time.Second, Handshake: true}
+	result := prober.ProbeNode(context.Background(), r.node())
+
+	if result.Err != nil {
+		t.Fatalf("unexpected error: %v", result.Err)
+	}
+	if !result.Reachable {
+		t.Error("expected the node to be reachable")
+	}
+	if result.PeerID != r.peerID {
+		t.Errorf("expected peer ID %s, got %s", r.peerID, result.PeerID)
+	}
+	if result.HandshakeLatency <= 0 {
+		t.Error("expected a handshake latency")
+	}
+}
+
+func TestProbeHandshakePeerIDMismatch(t *testing.T) {
+	r := newResponder(t)
+	other := newResponder(t)
+
+	node := r.node()
+	node.NetworkPubKey = other.networkKey
+
+	prober := &Prober{Timeout: 5 * time.Second, Handshake: true}
+	result := prober.ProbeNode(context.Background(), node)
+
+	if !result.Reachable {
+		t.Error("expected the node to be reachable")
+	}
+	if result.PeerID != "" {
+		t.Errorf("expected no peer ID, got %s", result.PeerID)
+	}
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "peer id mismatch") ||
+		!strings.Contains(result.Err.Error(), other.peerID) || !strings.Contains(result.Err.Error(), r.peerID) {
+		t.Errorf("expected a peer ID mismatch error naming both peer IDs, got %v", result.Err)
+	}
+}
+
+func TestProbeHandshakeTimeout(t *testing.T) {
+	// the listener accepts connections but never responds
+	address := listen(t, func(conn net.Conn) {
+		_, _ = bufio.NewReader(conn).ReadByte()
+		time.Sleep(2 * time.Second)
+	})
+
+	node := newResponder(t).node()
+	node.Address = address
+
+	prober := &Prober{Timeout: 200 * time.Millisecond, Handshake: true}
+	start := time.Now()
+	result := prober.ProbeNode(context.Background(), node)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("probe took %s, expected it to stop at the timeout", elapsed)
+	}
+	if !result.Reachable {
+		t.Error("expected the node to be reachable")
+	}
+	var netErr net.Error
+	if !errors.As(result.Err, &netErr) || !netErr.Timeout() {
+		t.Errorf("expected a timeout error, got %v", result.Err)
+	}
+}
+
+func TestProbeHandshakeProtocolRejected(t *testing.T) {
+	address := listen(t, func(conn net.Conn) {
+		muxer := mss.NewMultistreamMuxer[string]()
+		muxer.AddHandler("/tls/1.0.0", nil)
+		_, _, _ = muxer.Negotiate(conn)
+	})
+
+	node := newResponder(t).node()
+	node.Address = address
+
+	prober := &Prober{Timeout: 2 * time.Second, Handshake: true}
+	result := prober.ProbeNode(context.Background(), node)
+
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "error negotiating security protocol") {
+		t.Errorf("expected a negotiation error, got %v", result.Err)
+	}
+}
+
+func TestProbeDialContext(t *testing.T) {
+	r := newResponder(t)
+
+	// nodes are dialed through the injected dialer, so their addresses do not need to resolve
+	var dialed []string
+	prober := &Prober{
+		Timeout:   5 * time.Second,
+		Handshake: true,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed = append(dialed, address)
+			if address == "unreachable.invalid:3569" {
+				return nil, errors.New("connection refused")
+			}
+			return (&net.Dialer{}).DialContext(ctx, network, r.listener.Addr().String())
+		},
+		Concurrency: 1,
+	}
+
+	reachable := r.node()
+	reachable.Address = "access-001.invalid:3569"
+	unreachable := r.node()
+	unreachable.Address = "unreachable.invalid:3569"
+
+	results := prober.Probe(context.Background(), identities.IdentityList{reachable, unreachable})
+
+	if len(dialed) != 2 {
+		t.Fatalf("expected 2 dials, got %v", dialed)
+	}
+	if results[0].Err != nil || results[0].PeerID != r.peerID {
+		t.Errorf("expected the first node to pass, got %+v", results[0])
+	}
+	if results[1].Reachable || results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "connection refused") {
+		t.Errorf("expected the second node to be unreachable, got %+v", results[1])
+	}
+
+	failed := results.Failed()
+	if len(failed) != 1 || failed[0].Node.Address != unreachable.Address {
+		t.Errorf("expected only the unreachable node to fail, got %+v", failed)
+	}
+}
@@ -0,0 +1,92 @@
+package probe
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+)
+
+// Results is a list of probe results.
+type Results []Result
+
+// Failed returns the results of nodes that could not be reached, or failed the handshake.
+func (r Results) Failed() Results {
+	var failed Results
+	for _, result := range r {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// RoleSummary summarizes the probe results for the nodes of a role.
+type RoleSummary struct {
+	Role      identities.Role
+	Total     int
+	Reachable int
+	Failed    int
+
+	// AvgLatency and MaxLatency are the tcp connection latencies of the reachable nodes.
+	AvgLatency time.Duration
+	MaxLatency time.Duration
+}
+
+// Summary returns a summary for each role with probed nodes, in role order.
+func (r Results) Summary() []RoleSummary {
+	var summaries []RoleSummary
+	for _, role := range identities.Roles {
+		summary := RoleSummary{Role: role}
+
+		var total time.Duration
+		for _, result := range r {
+			if result.Node.Role != role {
+				continue
+			}
+
+			summary.Total++
+			if result.Err != nil {
+				summary.Failed++
+			}
+			if result.Reachable {
+				summary.Reachable++
+				total += result.Latency
+				summary.MaxLatency = max(summary.MaxLatency, result.Latency)
+			}
+		}
+
+		if summary.Total == 0 {
+			continue
+		}
+		if summary.Reachable > 0 {
+			summary.AvgLatency = total / time.Duration(summary.Reachable)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// Print prints the failures and a summary per role.
+func (r Results) Print() {
+	failed := r.Failed()
+	if len(failed) > 0 {
+		fmt.Println("Failures:")
+		for _, result := range failed {
+			fmt.Printf("  %-12s %s %s: %v\n", result.Node.Role, result.Node.NodeID, result.Node.Address, result.Err)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("%-12s %6s %9s %6s %10s %10s\n", "Role", "Nodes", "Reachable", "Failed", "Avg", "Max")
+	for _, summary := range r.Summary() {
+		fmt.Printf("%-12s %6d %9d %6d %10s %10s\n",
+			summary.Role,
+			summary.Total,
+			summary.Reachable,
+			summary.Failed,
+			summary.AvgLatency.Round(time.Millisecond),
+			summary.MaxLatency.Round(time.Millisecond),
+		)
+	}
+}
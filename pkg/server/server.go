@@ -0,0 +1,523 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/onflow/flow-go-sdk/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/snapshots"
+	"github.com/peterargue/flow-info/pkg/sporks"
+)
+
+const (
+	DefaultRefreshInterval = 10 * time.Minute
+	DefaultSnapshotTTL     = time.Minute
+
+	// defaultNetwork is used by endpoints that take an optional network query parameter.
+	defaultNetwork = "mainnet"
+
+	// snapshotTimeout limits how long loading the latest snapshot may take. Requests share the
+	// snapshot load, so it is not cancelled when the request that started it is.
+	snapshotTimeout = 30 * time.Second
+)
+
+// Server serves spork and identity information as a JSON API.
+//
+// The spork info is loaded on the first request and reloaded every RefreshInterval while Run is
+// running. Spork identities are cached until the spork info is reloaded, and the latest epoch of
+// each network is cached for SnapshotTTL. Concurrent requests for uncached identities or an
+// expired epoch share a single load.
+type Server struct {
+	// RefreshInterval is how often Run reloads the spork info. Defaults to DefaultRefreshInterval.
+	RefreshInterval time.Duration
+
+	// SnapshotTTL is how long the latest epoch of a network is cached.
+	SnapshotTTL time.Duration
+
+	// AccessNodes maps network names to the access node used to load the latest snapshot. Networks
+	// without an access node use the first access node of their live spork.
+	AccessNodes map[string]string
+
+	// LoadSporks loads the spork info. Defaults to sporks.Load.
+	LoadSporks func() (*sporks.SporkInfo, error)
+
+	// LoadIdentities loads a spork's identities. Defaults to Spork.Identities.
+	LoadIdentities func(spork *sporks.Spork) (identities.IdentityList, error)
+
+	// LatestSnapshot loads the latest snapshot for a network. Defaults to loading it from the
+	// network's access node.
+	LatestSnapshot func(ctx context.Context, network string) (*snapshots.Snapshot, error)
+
+	mu            sync.Mutex
+	info          *sporks.SporkInfo
+	generation    uint64
+	identities    map[string]identities.IdentityList
+	identityLoads map[string]*identityLoad
+	epochs        map[string]cachedEpoch
+	epochLoads    map[string]*epochLoad
+}
+
+type cachedEpoch struct {
+	epoch   *Epoch
+	fetched time.Time
+}
+
+// identityLoad is an in-progress load of a spork's identities. done is closed once it finishes.
+type identityLoad struct {
+	done chan struct{}
+	list identities.IdentityList
+	err  error
+}
+
+// epochLoad is an in-progress load of a network's latest epoch. done is closed once it finishes.
+type epochLoad struct {
+	done  chan struct{}
+	epoch *Epoch
+	err   error
+}
+
+// New returns a server with the default settings.
+func New() *Server {
+	s := &Server{
+		RefreshInterval: DefaultRefreshInterval,
+		SnapshotTTL:     DefaultSnapshotTTL,
+		LoadSporks:      sporks.Load,
+		LoadIdentities:  (*sporks.Spork).Identities,
+	}
+	s.LatestSnapshot = s.latestSnapshotFromAN
+	return s
+}
+
+// Handler returns the http handler for the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /networks", s.handleNetworks)
+	mux.HandleFunc("GET /sporks/{name}", s.handleSpork)
+	mux.HandleFunc("GET /sporks/{name}/identities", s.handleIdentities)
+	mux.HandleFunc("GET /height/{height}/spork", s.handleHeight)
+	mux.HandleFunc("GET /snapshot/latest/epoch", s.handleLatestEpoch)
+	return mux
+}
+
+// Refresh reloads the spork info. Cached identities are dropped, so changes to a spork's artefacts
+// are picked up.
+func (s *Server) Refresh() error {
+	load := s.LoadSporks
+	if load == nil {
+		load = sporks.Load
+	}
+
+	info, err := load()
+	if err != nil {
+		return fmt.Errorf("error loading sporks: %w", err)
+	}
+
+	s.mu.Lock()
+	s.info = info
+	s.generation++
+	s.identities = nil
+	s.identityLoads = nil
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Run reloads the spork info every RefreshInterval until the context is done. Errors are logged,
+// and the previously loaded spork info is kept.
+func (s *Server) Run(ctx context.Context) {
+	interval := s.RefreshInterval
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(); err != nil {
+				log.Printf("error refreshing spork info: %v", err)
+			}
+		}
+	}
+}
+
+// sporkInfo returns the spork info, loading it if it was not loaded yet.
+func (s *Server) sporkInfo() (*sporks.SporkInfo, error) {
+	s.mu.Lock()
+	info := s.info
+	s.mu.Unlock()
+
+	if info != nil {
+		return info, nil
+	}
+
+	err := s.Refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.info, nil
+}
+
+// Network is the summary of a network returned by /networks.
+type Network struct {
+	Live   string   `json:"live,omitempty"`
+	Sporks []string `json:"sporks"`
+}
+
+func (s *Server) handleNetworks(w http.ResponseWriter, r *http.Request) {
+	info, err := s.sporkInfo()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	networks := make(map[string]Network, len(info.Networks))
+	for name, network := range info.Networks {
+		list := make([]sporks.Spork, 0, len(network.Sporks))
+		for _, spork := range network.Sporks {
+			list = append(list, spork)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].RootHeight < list[j].RootHeight
+		})
+
+		var n Network
+		for _, spork := range list {
+			n.Sporks = append(n.Sporks, spork.Name)
+			if spork.Live {
+				n.Live = spork.Name
+			}
+		}
+		networks[name] = n
+	}
+
+	writeJSON(w, networks)
+}
+
+func (s *Server) handleSpork(w http.ResponseWriter, r *http.Request) {
+	spork, status, err := s.spork(r.PathValue("name"))
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+
+	writeJSON(w, spork)
+}
+
+func (s *Server) handleIdentities(w http.ResponseWriter, r *http.Request) {
+	spork, status, err := s.spork(r.PathValue("name"))
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+
+	list, err := s.sporkIdentities(r.Context(), spork)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("error loading identities: %w", err))
+		return
+	}
+
+	writeJSON(w, list)
+}
+
+// sporkIdentities returns the spork's identities, using the cached identities if they were loaded
+// since the last refresh. If the identities are already being loaded, it waits for that load
+// instead of starting another.
+func (s *Server) sporkIdentities(ctx context.Context, spork *sporks.Spork) (identities.IdentityList, error) {
+	s.mu.Lock()
+	if list, ok := s.identities[spork.Name]; ok {
+		s.mu.Unlock()
+		return list, nil
+	}
+
+	load, ok := s.identityLoads[spork.Name]
+	if !ok {
+		load = &identityLoad{done: make(chan struct{})}
+		if s.identityLoads == nil {
+			s.identityLoads = make(map[string]*identityLoad)
+		}
+		s.identityLoads[spork.Name] = load
+		go s.loadIdentities(spork, s.generation, load)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-load.done:
+		return load.list, load.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// loadIdentities loads the spork's identities, caches them and completes the load.
+func (s *Server) loadIdentities(spork *sporks.Spork, generation uint64, load *identityLoad) {
+	loadIdentities := s.LoadIdentities
+	if loadIdentities == nil {
+		loadIdentities = (*sporks.Spork).Identities
+	}
+
+	load.list, load.err = loadIdentities(spork)
+
+	s.mu.Lock()
+	if s.identityLoads[spork.Name] == load {
+		delete(s.identityLoads, spork.Name)
+	}
+	// identities loaded for spork info replaced by a refresh are not cached
+	if load.err == nil && s.generation == generation {
+		if s.identities == nil {
+			s.identities = make(map[string]identities.IdentityList)
+		}
+		s.identities[spork.Name] = load.list
+	}
+	s.mu.Unlock()
+
+	close(load.done)
+}
+
+func (s *Server) handleHeight(w http.ResponseWriter, r *http.Request) {
+	height, err := strconv.ParseUint(r.PathValue("height"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid height: %s", r.PathValue("height")))
+		return
+	}
+
+	info, err := s.sporkInfo()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	spork, err := info.SporkForHeight(network(r), height)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	writeJSON(w, spork)
+}
+
+// Epoch is the summary of the current epoch returned by /snapshot/latest/epoch.
+type Epoch struct {
+	Network         string                  `json:"network"`
+	ChainID         string                  `json:"chainId"`
+	View            uint64                  `json:"view"`
+	Counter         uint64                  `json:"counter"`
+	Phase           string                  `json:"phase"`
+	FirstView       uint64                  `json:"firstView"`
+	FinalView       uint64                  `json:"finalView"`
+	TargetEndTime   uint64                  `json:"targetEndTime"`
+	InEpochFallback bool                    `json:"inEpochFallback"`
+	Participants    map[identities.Role]int `json:"participants"`
+}
+
+func (s *Server) handleLatestEpoch(w http.ResponseWriter, r *http.Request) {
+	network := network(r)
+	status, err := s.checkNetwork(network)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+
+	epoch, err := s.latestEpoch(r.Context(), network)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("error loading latest snapshot: %w", err))
+		return
+	}
+
+	writeJSON(w, epoch)
+}
+
+// latestEpoch returns the latest epoch of the network, using the cached epoch while it is fresh.
+// If the epoch is already being loaded, it waits for that load instead of starting another.
+func (s *Server) latestEpoch(ctx context.Context, network string) (*Epoch, error) {
+	s.mu.Lock()
+	if cached, ok := s.epochs[network]; ok && time.Since(cached.fetched) < s.SnapshotTTL {
+		s.mu.Unlock()
+		return cached.epoch, nil
+	}
+
+	load, ok := s.epochLoads[network]
+	if !ok {
+		load = &epochLoad{done: make(chan struct{})}
+		if s.epochLoads == nil {
+			s.epochLoads = make(map[string]*epochLoad)
+		}
+		s.epochLoads[network] = load
+		go s.loadEpoch(network, load)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-load.done:
+		return load.epoch, load.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// loadEpoch loads the latest snapshot of the network, caches its epoch and completes the load.
+func (s *Server) loadEpoch(network string, load *epochLoad) {
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotTimeout)
+	defer cancel()
+
+	latest := s.LatestSnapshot
+	if latest == nil {
+		latest = s.latestSnapshotFromAN
+	}
+
+	snapshot, err := latest(ctx, network)
+	if err != nil {
+		load.err = err
+	} else {
+		load.epoch = epochSummary(network, snapshot)
+	}
+
+	s.mu.Lock()
+	delete(s.epochLoads, network)
+	if load.err == nil {
+		if s.epochs == nil {
+			s.epochs = make(map[string]cachedEpoch)
+		}
+		s.epochs[network] = cachedEpoch{epoch: load.epoch, fetched: time.Now()}
+	}
+	s.mu.Unlock()
+
+	close(load.done)
+}
+
+func epochSummary(network string, snapshot *snapshots.Snapshot) *Epoch {
+	entry := snapshot.SealingSegment.ProtocolStateEntry().EpochEntry
+	setup := entry.CurrentEpochSetup
+
+	// the epoch phase is derived from the service events received for the next epoch
+	phase := "staking"
+	switch {
+	case entry.NextEpochCommit.Counter != 0:
+		phase = "committed"
+	case entry.NextEpochSetup.Counter != 0:
+		phase = "setup"
+	}
+
+	participants := make(map[identities.Role]int)
+	for _, p := range entry.CurrentEpochParticipants().Active() {
+		participants[p.Role]++
+	}
+
+	return &Epoch{
+		Network:         network,
+		ChainID:         snapshot.Params.ChainID,
+		View:            snapshot.QuorumCertificate.View,
+		Counter:         setup.Counter,
+		Phase:           phase,
+		FirstView:       setup.FirstView,
+		FinalView:       entry.CurrentEpochFinalView(),
+		TargetEndTime:   setup.TargetEndTime,
+		InEpochFallback: entry.InEpochFallback(),
+		Participants:    participants,
+	}
+}
+
+// spork returns the spork with the given name, and the http status to use if it fails.
+func (s *Server) spork(name string) (*sporks.Spork, int, error) {
+	info, err := s.sporkInfo()
+	if err != nil {
+		return nil, http.StatusBadGateway, err
+	}
+
+	spork, err := info.Spork(name)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+
+	return spork, http.StatusOK, nil
+}
+
+// checkNetwork checks that the network is in the spork info, or has a configured access node. It
+// returns the http status to use if it fails.
+func (s *Server) checkNetwork(network string) (int, error) {
+	if _, ok := s.AccessNodes[network]; ok {
+		return http.StatusOK, nil
+	}
+
+	info, err := s.sporkInfo()
+	if err != nil {
+		return http.StatusBadGateway, err
+	}
+
+	if _, ok := info.Networks[network]; !ok {
+		return http.StatusNotFound, fmt.Errorf("network %s not found", network)
+	}
+
+	return http.StatusOK, nil
+}
+
+// latestSnapshotFromAN loads the latest snapshot from the network's access node.
+func (s *Server) latestSnapshotFromAN(ctx context.Context, network string) (*snapshots.Snapshot, error) {
+	accessNode := s.AccessNodes[network]
+	if accessNode == "" {
+		info, err := s.sporkInfo()
+		if err != nil {
+			return nil, err
+		}
+
+		spork, err := info.LatestSpork(network)
+		if err != nil {
+			return nil, err
+		}
+		if spork == nil || len(spork.AccessNodes) == 0 {
+			return nil, fmt.Errorf("no access node found for network %s", network)
+		}
+		accessNode = spork.AccessNodes[0]
+	}
+
+	accessClient, err := client.New(accessNode,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(20*1024*1024)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating access node client: %w", err)
+	}
+	defer accessClient.Close()
+
+	return snapshots.LoadLatestPartsFromAN(ctx, accessClient, snapshots.PartParams|snapshots.PartQuorumCertificate|snapshots.PartProtocolState)
+}
+
+// network returns the network from the request's query, or the default network.
+func network(r *http.Request) string {
+	if name := r.URL.Query().Get("network"); name != "" {
+		return name
+	}
+	return defaultNetwork
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
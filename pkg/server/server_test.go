@@ -0,0 +1,563 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/snapshots"
+	"github.com/peterargue/flow-info/pkg/snapshots/snapshotstest"
+	"github.com/peterargue/flow-info/pkg/sporks"
+)
+
+func testSporkInfo(nodeInfo string) *sporks.SporkInfo {
+	return &sporks.SporkInfo{
+		Networks: map[string]sporks.Sporks{
+			"mainnet": {Sporks: map[string]sporks.Spork{
+				"mainnet25": {Name: "mainnet25", RootHeight: 100},
+				"mainnet26": {
+					Name:           "mainnet26",
+					Live:           true,
+					RootHeight:     200,
+					StateArtefacts: sporks.StateArtefacts{NodeInfo: nodeInfo},
+				},
+			}},
+			"testnet": {Sporks: map[string]sporks.Spork{
+				"testnet50": {Name: "testnet50", Live: true, RootHeight: 10},
+			}},
+		},
+	}
+}
+
+// testServer is a server with fake loaders that count their calls.
+type testServer struct {
+	*Server
+	url string
+
+	sporkLoads    atomic.Int32
+	identityLoads atomic.Int32
+	snapshotLoads atomic.Int32
+	nodeInfo      atomic.Value
+}
+
+func newTestServer(t *testing.T, snapshot *snapshots.Snapshot) *testServer {
+	t.Helper()
+
+	ts := &testServer{Server: New()}
+	ts.nodeInfo.Store("node-infos-v1.json")
+
+	ts.LoadSporks = func() (*sporks.SporkInfo, error) {
+		ts.sporkLoads.Add(1)
+		return testSporkInfo(ts.nodeInfo.Load().(string)), nil
+	}
+	ts.LoadIdentities = func(spork *sporks.Spork) (identities.IdentityList, error) {
+		ts.identityLoads.Add(1)
+		return identities.IdentityList{{
+			Role:    identities.RoleAccess,
+			NodeID:  strings.Repeat("01", 32),
+			Address: spork.StateArtefacts.NodeInfo,
+		}}, nil
+	}
+	ts.LatestSnapshot = func(ctx context.Context, network string) (*snapshots.Snapshot, error) {
+		ts.snapshotLoads.Add(1)
+		return snapshot, nil
+	}
+
+	server := httptest.NewServer(ts.Handler())
+	t.Cleanup(server.Close)
+	ts.url = server.URL
+
+	return ts
+}
+
+func buildSnapshot(t *testing.T) *snapshots.Snapshot {
+	t.Helper()
+
+	snapshot, err := snapshotstest.NewBuilder().
+		WithSeed(3).
+		WithChainID("flow-mainnet").
+		WithEpochCounter(42).
+		WithNodes(identities.RoleAccess, 3).
+		Build()
+	if err != nil {
+		t.Fatalf("error building snapshot: %v", err)
+	}
+	return snapshot
+}
+
+// get requests the path, checks the status and decodes the response into value if it is not nil.
+func get(t *testing.T, url, path string, status int, value interface{}) {
+	t.Helper()
+
+	res, err := http.Get(url + path)
+	if err != nil {
+		t.Fatalf("error requesting %s: %v", path, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != status {
+		t.Fatalf("%s: expected status %d, got %d", path, status, res.StatusCode)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s: expected a json content type, got %s", path, ct)
+	}
+
+	if value == nil {
+		return
+	}
+	if err := json.NewDecoder(res.Body).Decode(value); err != nil {
+		t.Fatalf("%s: error decoding response: %v", path, err)
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	ts := newTestServer(t, nil)
+
+	var networks map[string]Network
+	get(t, ts.url, "/networks", http.StatusOK, &networks)
+
+	mainnet := networks["mainnet"]
+	if mainnet.Live != "mainnet26" {
+		t.Errorf("expected live spork mainnet26, got %s", mainnet.Live)
+	}
+	if strings.Join(mainnet.Sporks, ",") != "mainnet25,mainnet26" {
+		t.Errorf("expected sporks ordered by root height, got %v", mainnet.Sporks)
+	}
+	if networks["testnet"].Live != "testnet50" {
+		t.Errorf("expected live spork testnet50, got %s", networks["testnet"].Live)
+	}
+}
+
+func TestSpork(t *testing.T) {
+	ts := newTestServer(t, nil)
+
+	var spork sporks.Spork
+	get(t, ts.url, "/sporks/mainnet25", http.StatusOK, &spork)
+	if spork.Name != "mainnet25" || spork.RootHeight != 100 {
+		t.Errorf("unexpected spork %+v", spork)
+	}
+
+	var body map[string]string
+	get(t, ts.url, "/sporks/mainnet99", http.StatusNotFound, &body)
+	if body["error"] != "spork mainnet99 not found" {
+		t.Errorf("unexpected error %q", body["error"])
+	}
+	get(t, ts.url, "/sporks/unknown", http.StatusNotFound, nil)
+}
+
+func TestIdentities(t *testing.T) {
+	ts := newTestServer(t, nil)
+
+	var list identities.IdentityList
+	get(t, ts.url, "/sporks/mainnet26/identities", http.StatusOK, &list)
+	if len(list) != 1 || list[0].Address != "node-infos-v1.json" {
+		t.Fatalf("unexpected identities %+v", list)
+	}
+
+	get(t, ts.url, "/sporks/mainnet26/identities", http.StatusOK, nil)
+	if n := ts.identityLoads.Load(); n != 1 {
+		t.Errorf("expected the identities to be cached, got %d loads", n)
+	}
+
+	get(t, ts.url, "/sporks/mainnet99/identities", http.StatusNotFound, nil)
+	if n := ts.identityLoads.Load(); n != 1 {
+		t.Errorf("expected no load for an unknown spork, got %d loads", n)
+	}
+}
+
+func TestIdentitiesError(t *testing.T) {
+	ts := newTestServer(t, nil)
+
+	fail := true
+	load := ts.LoadIdentities
+	ts.LoadIdentities = func(spork *sporks.Spork) (identities.IdentityList, error) {
+		if fail {
+			return nil, errors.New("unavailable")
+		}
+		return load(spork)
+	}
+
+	get(t, ts.url, "/sporks/mainnet26/identities", http.StatusBadGateway, nil)
+
+	// failed loads are not cached
+	fail = false
+	get(t, ts.url, "/sporks/mainnet26/identities", http.StatusOK, nil)
+}
+
+func TestIdentitiesConcurrentMisses(t *testing.T) {
+	ts := newTestServer(t, nil)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	load := ts.LoadIdentities
+	ts.LoadIdentities = func(spork *sporks.Spork) (identities.IdentityList, error) {
+		once.Do(func() { close(started) })
+		<-release
+		return load(spork)
+	}
+
+	const requests = 10
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get(t, ts.url, "/sporks/mainnet26/identities", http.StatusOK, nil)
+		}()
+	}
+
+	<-started
+	// give the other requests time to find the load in progress
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := ts.identityLoads.Load(); n != 1 {
+		t.Errorf("expected concurrent requests to share 1 load, got %d", n)
+	}
+}
+
+func TestIdentitiesRefreshDuringLoad(t *testing.T) {
+	ts := newTestServer(t, nil)
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	load := ts.LoadIdentities
+	ts.LoadIdentities = func(spork *sporks.Spork) (identities.IdentityList, error) {
+		started <- struct{}{}
+		<-release
+		return load(spork)
+	}
+
+	info, err := ts.sporkInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := info.Spork("mainnet26")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := ts.sporkIdentities(context.Background(), old)
+		done <- err
+	}()
+	<-started
+
+	// requests after a refresh don't wait for a load of the previous spork info
+	ts.nodeInfo.Store("node-infos-v2.json")
+	if err := ts.Refresh(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var list identities.IdentityList
+	get(t, ts.url, "/sporks/mainnet26/identities", http.StatusOK, &list)
+	if list[0].Address != "node-infos-v2.json" {
+		t.Errorf("expected the identities of the refreshed spork, got %+v", list)
+	}
+	if n := ts.identityLoads.Load(); n != 2 {
+		t.Errorf("expected 2 identity loads, got %d", n)
+	}
+}
+
+func TestHeight(t *testing.T) {
+	ts := newTestServer(t, nil)
+
+	tests := []struct {
+		path   string
+		status int
+		spork  string
+	}{
+		{path: "/height/150/spork", status: http.StatusOK, spork: "mainnet25"},
+		{path: "/height/200/spork?network=mainnet", status: http.StatusOK, spork: "mainnet26"},
+		{path: "/height/1000000/spork", status: http.StatusOK, spork: "mainnet26"},
+		{path: "/height/10/spork?network=testnet", status: http.StatusOK, spork: "testnet50"},
+		{path: "/height/50/spork", status: http.StatusNotFound},
+		{path: "/height/150/spork?network=unknown", status: http.StatusNotFound},
+		{path: "/height/abc/spork", status: http.StatusBadRequest},
+		{path: "/height/-1/spork", status: http.StatusBadRequest},
+		{path: "/height/18446744073709551616/spork", status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			var spork sporks.Spork
+			if test.status != http.StatusOK {
+				get(t, ts.url, test.path, test.status, nil)
+				return
+			}
+			get(t, ts.url, test.path, test.status, &spork)
+			if spork.Name != test.spork {
+				t.Errorf("expected spork %s, got %s", test.spork, spork.Name)
+			}
+		})
+	}
+}
+
+func TestLatestEpoch(t *testing.T) {
+	ts := newTestServer(t, buildSnapshot(t))
+	ts.SnapshotTTL = time.Hour
+
+	var epoch Epoch
+	get(t, ts.url, "/snapshot/latest/epoch", http.StatusOK, &epoch)
+
+	if epoch.Network != "mainnet" || epoch.ChainID != "flow-mainnet" || epoch.Counter != 42 {
+		t.Errorf("unexpected epoch %+v", epoch)
+	}
+	if epoch.Phase != "staking" {
+		t.Errorf("expected the staking phase, got %s", epoch.Phase)
+	}
+	if epoch.Participants[identities.RoleAccess] != 3 {
+		t.Errorf("expected 3 access nodes, got %v", epoch.Participants)
+	}
+
+	// cache hit
+	get(t, ts.url, "/snapshot/latest/epoch?network=mainnet", http.StatusOK, nil)
+	if n := ts.snapshotLoads.Load(); n != 1 {
+		t.Errorf("expected the epoch to be cached, got %d loads", n)
+	}
+
+	// cache miss for another network
+	get(t, ts.url, "/snapshot/latest/epoch?network=testnet", http.StatusOK, &epoch)
+	if n := ts.snapshotLoads.Load(); n != 2 || epoch.Network != "testnet" {
+		t.Errorf("expected a load for testnet, got %d loads for %s", n, epoch.Network)
+	}
+
+	// cache miss once the epoch expired
+	ts.SnapshotTTL = 0
+	get(t, ts.url, "/snapshot/latest/epoch", http.StatusOK, nil)
+	if n := ts.snapshotLoads.Load(); n != 3 {
+		t.Errorf("expected the expired epoch to be loaded again, got %d loads", n)
+	}
+}
+
+func TestLatestEpochUnknownNetwork(t *testing.T) {
+	ts := newTestServer(t, buildSnapshot(t))
+
+	get(t, ts.url, "/snapshot/latest/epoch?network=bogus", http.StatusNotFound, nil)
+	if n := ts.snapshotLoads.Load(); n != 0 {
+		t.Errorf("expected no load for an unknown network, got %d loads", n)
+	}
+
+	// networks with a configured access node don't need to be in the spork info
+	ts.AccessNodes = map[string]string{"localnet": "localhost:3569"}
+	var epoch Epoch
+	get(t, ts.url, "/snapshot/latest/epoch?network=localnet", http.StatusOK, &epoch)
+	if epoch.Network != "localnet" {
+		t.Errorf("expected the localnet epoch, got %+v", epoch)
+	}
+}
+
+func TestLatestEpochError(t *testing.T) {
+	ts := newTestServer(t, buildSnapshot(t))
+	ts.SnapshotTTL = time.Hour
+
+	snapshot := buildSnapshot(t)
+	fail := true
+	ts.LatestSnapshot = func(ctx context.Context, network string) (*snapshots.Snapshot, error) {
+		ts.snapshotLoads.Add(1)
+		if fail {
+			return nil, errors.New("unavailable")
+		}
+		return snapshot, nil
+	}
+
+	get(t, ts.url, "/snapshot/latest/epoch", http.StatusBadGateway, nil)
+
+	// failed loads are not cached
+	fail = false
+	get(t, ts.url, "/snapshot/latest/epoch", http.StatusOK, nil)
+	if n := ts.snapshotLoads.Load(); n != 2 {
+		t.Errorf("expected 2 loads, got %d", n)
+	}
+}
+
+func TestLatestEpochConcurrentMisses(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ts.SnapshotTTL = time.Hour
+
+	snapshot := buildSnapshot(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ts.LatestSnapshot = func(ctx context.Context, network string) (*snapshots.Snapshot, error) {
+		if ts.snapshotLoads.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return snapshot, nil
+	}
+
+	const requests = 10
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get(t, ts.url, "/snapshot/latest/epoch", http.StatusOK, nil)
+		}()
+	}
+
+	<-started
+	// give the other requests time to find the load in progress
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := ts.snapshotLoads.Load(); n != 1 {
+		t.Errorf("expected concurrent requests to share 1 load, got %d", n)
+	}
+}
+
+func TestLatestEpochCancelledRequest(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ts.SnapshotTTL = time.Hour
+
+	snapshot := buildSnapshot(t)
+	release := make(chan struct{})
+	ts.LatestSnapshot = func(ctx context.Context, network string) (*snapshots.Snapshot, error) {
+		ts.snapshotLoads.Add(1)
+		select {
+		case <-release:
+			return snapshot, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// the request that starts the load gives up, but the load continues for later requests
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := ts.latestEpoch(ctx, "mainnet"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the request to time out, got %v", err)
+	}
+
+	close(release)
+	epoch, err := ts.latestEpoch(context.Background(), "mainnet")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if epoch.Counter != 42 {
+		t.Errorf("unexpected epoch %+v", epoch)
+	}
+	if n := ts.snapshotLoads.Load(); n != 1 {
+		t.Errorf("expected 1 load, got %d", n)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	ts := newTestServer(t, nil)
+
+	var list identities.IdentityList
+	get(t, ts.url, "/sporks/mainnet26/identities", http.StatusOK, &list)
+	if list[0].Address != "node-infos-v1.json" {
+		t.Fatalf("unexpected identities %+v", list)
+	}
+
+	// sporks.json is edited to point at a new node infos file
+	ts.nodeInfo.Store("node-infos-v2.json")
+
+	var spork sporks.Spork
+	get(t, ts.url, "/sporks/mainnet26", http.StatusOK, &spork)
+	if spork.StateArtefacts.NodeInfo != "node-infos-v1.json" {
+		t.Fatalf("expected the loaded spork info to be used until refreshed, got %s", spork.StateArtefacts.NodeInfo)
+	}
+
+	if err := ts.Refresh(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	get(t, ts.url, "/sporks/mainnet26", http.StatusOK, &spork)
+	if spork.StateArtefacts.NodeInfo != "node-infos-v2.json" {
+		t.Errorf("expected the refreshed spork, got %s", spork.StateArtefacts.NodeInfo)
+	}
+	get(t, ts.url, "/sporks/mainnet26/identities", http.StatusOK, &list)
+	if list[0].Address != "node-infos-v2.json" {
+		t.Errorf("expected the identities to be loaded again after a refresh, got %+v", list)
+	}
+	if n := ts.identityLoads.Load(); n != 2 {
+		t.Errorf("expected 2 identity loads, got %d", n)
+	}
+}
+
+func TestRefreshError(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ts.LoadSporks = func() (*sporks.SporkInfo, error) {
+		return nil, errors.New("unavailable")
+	}
+
+	get(t, ts.url, "/networks", http.StatusBadGateway, nil)
+	get(t, ts.url, "/sporks/mainnet26", http.StatusBadGateway, nil)
+	get(t, ts.url, "/height/150/spork", http.StatusBadGateway, nil)
+}
+
+func TestRun(t *testing.T) {
+	var loads atomic.Int32
+	s := &Server{
+		RefreshInterval: 10 * time.Millisecond,
+		LoadSporks: func() (*sporks.SporkInfo, error) {
+			loads.Add(1)
+			return testSporkInfo(""), nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for loads.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if n := loads.Load(); n < 2 {
+		t.Errorf("expected the spork info to be refreshed, got %d loads", n)
+	}
+}
+
+func TestStructLiteral(t *testing.T) {
+	snapshot := buildSnapshot(t)
+	s := &Server{
+		LoadSporks: func() (*sporks.SporkInfo, error) {
+			return testSporkInfo("node-infos.json"), nil
+		},
+		LoadIdentities: func(spork *sporks.Spork) (identities.IdentityList, error) {
+			return identities.IdentityList{}, nil
+		},
+		LatestSnapshot: func(ctx context.Context, network string) (*snapshots.Snapshot, error) {
+			return snapshot, nil
+		},
+	}
+
+	// the zero refresh interval falls back to the default instead of panicking
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.Run(ctx)
+
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	get(t, ts.URL, "/networks", http.StatusOK, nil)
+	get(t, ts.URL, "/sporks/mainnet26", http.StatusOK, nil)
+	get(t, ts.URL, "/sporks/mainnet26/identities", http.StatusOK, nil)
+	get(t, ts.URL, "/height/150/spork", http.StatusOK, nil)
+	get(t, ts.URL, "/snapshot/latest/epoch", http.StatusOK, nil)
+}
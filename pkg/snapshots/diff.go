@@ -0,0 +1,170 @@
+package snapshots
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+)
+
+// ChangeSet contains the changes between two snapshots.
+type ChangeSet struct {
+	Params       []FieldChange      `json:"params,omitempty"`
+	Epoch        []FieldChange      `json:"epoch,omitempty"`
+	Identities   identities.Changes `json:"identities,omitempty"`
+	Reassigned   []ClusterChange    `json:"reassigned,omitempty"`
+	ClusterCount *FieldChange       `json:"clusterCount,omitempty"`
+}
+
+// FieldChange is a change to a single field.
+type FieldChange struct {
+	Field string `json:"field,omitempty"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// ClusterChange is a collection node that moved between clusters. A cluster of -1 means the node
+// was not assigned to a cluster.
+type ClusterChange struct {
+	NodeID     string `json:"nodeId"`
+	OldCluster int    `json:"oldCluster"`
+	NewCluster int    `json:"newCluster"`
+}
+
+// Empty returns true if there are no changes.
+func (d *ChangeSet) Empty() bool {
+	return len(d.Params) == 0 &&
+		len(d.Epoch) == 0 &&
+		len(d.Identities) == 0 &&
+		len(d.Reassigned) == 0 &&
+		d.ClusterCount == nil
+}
+
+// Diff returns the changes from snapshot a to snapshot b, comparing their params and current epochs.
+func Diff(a, b *Snapshot) *ChangeSet {
+	d := &ChangeSet{}
+
+	d.Params = diffFields([]fieldPair{
+		{"ChainID", a.Params.ChainID, b.Params.ChainID},
+		{"SporkID", a.Params.SporkID, b.Params.SporkID},
+		{"SporkRootBlockHeight", a.Params.SporkRootBlockHeight, b.Params.SporkRootBlockHeight},
+		{"ProtocolVersion", a.Params.ProtocolVersion, b.Params.ProtocolVersion},
+	})
+
+	entryA := a.SealingSegment.ProtocolStateEntry().EpochEntry
+	entryB := b.SealingSegment.ProtocolStateEntry().EpochEntry
+	setupA := entryA.CurrentEpochSetup
+	setupB := entryB.CurrentEpochSetup
+
+	d.Epoch = diffFields([]fieldPair{
+		{"Counter", setupA.Counter, setupB.Counter},
+		{"FirstView", setupA.FirstView, setupB.FirstView},
+		{"DKGPhase1FinalView", setupA.DKGPhase1FinalView, setupB.DKGPhase1FinalView},
+		{"DKGPhase2FinalView", setupA.DKGPhase2FinalView, setupB.DKGPhase2FinalView},
+		{"DKGPhase3FinalView", setupA.DKGPhase3FinalView, setupB.DKGPhase3FinalView},
+		{"FinalView", entryA.CurrentEpochFinalView(), entryB.CurrentEpochFinalView()},
+		{"TargetDuration", setupA.TargetDuration, setupB.TargetDuration},
+		{"TargetEndTime", setupA.TargetEndTime, setupB.TargetEndTime},
+		{"EpochFallbackTriggered", entryA.EpochFallbackTriggered, entryB.EpochFallbackTriggered},
+	})
+
+	d.Identities = identities.Diff(setupA.Identities(), setupB.Identities())
+
+	clustersA := setupA.Clusters()
+	clustersB := setupB.Clusters()
+	if len(clustersA) != len(clustersB) {
+		d.ClusterCount = &FieldChange{
+			Field: "Clusters",
+			Old:   fmt.Sprint(len(clustersA)),
+			New:   fmt.Sprint(len(clustersB)),
+		}
+	}
+
+	assignmentsA := clusterAssignments(clustersA)
+	assignmentsB := clusterAssignments(clustersB)
+	for nodeID, oldCluster := range assignmentsA {
+		newCluster, ok := assignmentsB[nodeID]
+		if !ok {
+			newCluster = -1
+		}
+		if oldCluster != newCluster {
+			d.Reassigned = append(d.Reassigned, ClusterChange{NodeID: nodeID, OldCluster: oldCluster, NewCluster: newCluster})
+		}
+	}
+	for nodeID, newCluster := range assignmentsB {
+		if _, ok := assignmentsA[nodeID]; !ok {
+			d.Reassigned = append(d.Reassigned, ClusterChange{NodeID: nodeID, OldCluster: -1, NewCluster: newCluster})
+		}
+	}
+	sort.Slice(d.Reassigned, func(i, j int) bool {
+		return d.Reassigned[i].NodeID < d.Reassigned[j].NodeID
+	})
+
+	return d
+}
+
+// Print prints the diff to stdout.
+func (d *ChangeSet) Print() {
+	if d.Empty() {
+		fmt.Println("No changes")
+		return
+	}
+
+	printFieldChanges := func(title string, changes []FieldChange) {
+		if len(changes) == 0 {
+			return
+		}
+		fmt.Printf("%s:\n", title)
+		for _, change := range changes {
+			fmt.Printf("  %s: %s -> %s\n", change.Field, change.Old, change.New)
+		}
+	}
+
+	printFieldChanges("Params", d.Params)
+	printFieldChanges("Epoch", d.Epoch)
+
+	if len(d.Identities) > 0 {
+		fmt.Printf("Identities:\n")
+		for _, change := range d.Identities {
+			fmt.Printf("  %s\n", change)
+		}
+	}
+	if d.ClusterCount != nil {
+		fmt.Printf("Clusters: %s -> %s\n", d.ClusterCount.Old, d.ClusterCount.New)
+	}
+	if len(d.Reassigned) > 0 {
+		fmt.Printf("Cluster Reassignments:\n")
+		for _, change := range d.Reassigned {
+			fmt.Printf("  %s: %d -> %d\n", change.NodeID, change.OldCluster, change.NewCluster)
+		}
+	}
+}
+
+type fieldPair struct {
+	field    string
+	old, new interface{}
+}
+
+func diffFields(pairs []fieldPair) []FieldChange {
+	var changes []FieldChange
+	for _, pair := range pairs {
+		if pair.old != pair.new {
+			changes = append(changes, FieldChange{
+				Field: pair.field,
+				Old:   fmt.Sprint(pair.old),
+				New:   fmt.Sprint(pair.new),
+			})
+		}
+	}
+	return changes
+}
+
+func clusterAssignments(clusters []identities.IdentityList) map[string]int {
+	assignments := make(map[string]int)
+	for i, cluster := range clusters {
+		for _, identity := range cluster {
+			assignments[identity.NodeID] = i
+		}
+	}
+	return assignments
+}
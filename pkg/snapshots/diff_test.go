@@ -0,0 +1,124 @@
+package snapshots_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/snapshots"
+	"github.com/peterargue/flow-info/pkg/snapshots/snapshotstest"
+)
+
+func buildClusteredSnapshot(t *testing.T, clusters int) *snapshots.Snapshot {
+	t.Helper()
+
+	snapshot, err := snapshotstest.NewBuilder().
+		WithSeed(3).
+		WithNodes(identities.RoleCollection, 4).
+		WithClusters(clusters).
+		Build()
+	if err != nil {
+		t.Fatalf("error building snapshot: %v", err)
+	}
+	return snapshot
+}
+
+// updateEntry applies the update to the snapshot's protocol state entry.
+func updateEntry(s *snapshots.Snapshot, update func(e *snapshots.EpochEntry)) {
+	for id, entry := range s.SealingSegment.ProtocolStateEntries {
+		update(&entry.EpochEntry)
+		s.SealingSegment.ProtocolStateEntries[id] = entry
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Run("identical", func(t *testing.T) {
+		d := snapshots.Diff(buildClusteredSnapshot(t, 1), buildClusteredSnapshot(t, 1))
+		if !d.Empty() {
+			t.Errorf("expected no changes, got %+v", d)
+		}
+	})
+
+	t.Run("params and epoch", func(t *testing.T) {
+		a := buildClusteredSnapshot(t, 1)
+		b := buildClusteredSnapshot(t, 1)
+		b.Params.ProtocolVersion = 2
+		b.Params.SporkRootBlockHeight = 100
+		updateEntry(b, func(e *snapshots.EpochEntry) {
+			e.CurrentEpochSetup.Counter = 5
+			e.CurrentEpoch.EpochExtensions = []snapshots.EpochExtension{{FirstView: e.CurrentEpochSetup.FinalView + 1, FinalView: 200_000}}
+			e.EpochFallbackTriggered = true
+		})
+
+		d := snapshots.Diff(a, b)
+		wantParams := []snapshots.FieldChange{
+			{Field: "SporkRootBlockHeight", Old: "0", New: "100"},
+			{Field: "ProtocolVersion", Old: "0", New: "2"},
+		}
+		if !reflect.DeepEqual(d.Params, wantParams) {
+			t.Errorf("expected params changes %+v, got %+v", wantParams, d.Params)
+		}
+		wantEpoch := []snapshots.FieldChange{
+			{Field: "Counter", Old: "0", New: "5"},
+			{Field: "FinalView", Old: "99999", New: "200000"},
+			{Field: "EpochFallbackTriggered", Old: "false", New: "true"},
+		}
+		if !reflect.DeepEqual(d.Epoch, wantEpoch) {
+			t.Errorf("expected epoch changes %+v, got %+v", wantEpoch, d.Epoch)
+		}
+		if len(d.Identities) != 0 || len(d.Reassigned) != 0 || d.ClusterCount != nil {
+			t.Errorf("unexpected changes %+v", d)
+		}
+	})
+
+	t.Run("identities", func(t *testing.T) {
+		a := buildClusteredSnapshot(t, 1)
+		b := buildClusteredSnapshot(t, 1)
+		var left identities.NodeInfo
+		updateEntry(b, func(e *snapshots.EpochEntry) {
+			participants := append([]snapshots.Identity{}, e.CurrentEpochSetup.Participants...)
+			participants[0].Address = "moved.localnet:3569"
+			left = participants[len(participants)-1].NodeInfo
+			e.CurrentEpochSetup.Participants = participants[:len(participants)-1]
+		})
+
+		d := snapshots.Diff(a, b)
+		first := a.CurrentEpochSetup().Participants[0]
+		want := identities.Changes{
+			{Kind: identities.ChangeAddress, NodeID: first.NodeID, Role: first.Role, Old: first.Address, New: "moved.localnet:3569"},
+			{Kind: identities.ChangeLeft, NodeID: left.NodeID, Role: left.Role, Old: left.Address},
+		}
+		if !reflect.DeepEqual(d.Identities, want) {
+			t.Errorf("expected identity changes %+v, got %+v", want, d.Identities)
+		}
+	})
+
+	t.Run("clusters", func(t *testing.T) {
+		a := buildClusteredSnapshot(t, 1)
+		b := buildClusteredSnapshot(t, 2)
+
+		// an unassigned collector is reported with cluster -1
+		unassigned := b.CurrentEpochSetup().Assignments[1][1]
+		updateEntry(b, func(e *snapshots.EpochEntry) {
+			e.CurrentEpochSetup.Assignments[1] = e.CurrentEpochSetup.Assignments[1][:1]
+		})
+
+		d := snapshots.Diff(a, b)
+		if d.ClusterCount == nil || d.ClusterCount.Old != "1" || d.ClusterCount.New != "2" {
+			t.Errorf("expected the cluster count to change from 1 to 2, got %+v", d.ClusterCount)
+		}
+
+		// the builder assigns collectors round-robin, so the second and fourth move
+		collectors := a.CurrentEpochSetup().Assignments[0]
+		want := []snapshots.ClusterChange{
+			{NodeID: collectors[1], OldCluster: 0, NewCluster: 1},
+			{NodeID: unassigned, OldCluster: 0, NewCluster: -1},
+		}
+		if collectors[3] != unassigned {
+			t.Fatalf("expected %s to be the unassigned collector", collectors[3])
+		}
+		if !reflect.DeepEqual(d.Reassigned, want) {
+			t.Errorf("expected reassignments %+v, got %+v", want, d.Reassigned)
+		}
+	})
+}
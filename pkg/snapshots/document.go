@@ -0,0 +1,85 @@
+package snapshots
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/peterargue/flow-info/internal"
+)
+
+// Document is a snapshot that retains its original json. It can be edited through the embedded
+// Snapshot and encoded again without losing fields or formatting that Snapshot does not model.
+// Fields and map entries deleted from the Snapshot are removed. Encoding an unmodified document
+// reproduces the original json.
+type Document struct {
+	Snapshot
+	raw    interface{}
+	base   interface{}
+	format internal.JSONFormat
+}
+
+// LoadDocument loads a snapshot document from a local file or url.
+func LoadDocument(url string) (*Document, error) {
+	data, err := read(url)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeDocument(data)
+}
+
+// DecodeDocument decodes a snapshot document from json.
+func DecodeDocument(data []byte) (*Document, error) {
+	var doc Document
+	err := json.Unmarshal(data, &doc.Snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling snapshot json: %w", err)
+	}
+
+	doc.raw, err = internal.ParseJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing snapshot json: %w", err)
+	}
+	doc.format = internal.DetectJSONFormat(data)
+
+	// the snapshot encoded again holds the fields it models, which tells fields deleted from the
+	// snapshot apart from fields it does not know
+	encoded, err := json.Marshal(doc.Snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling snapshot json: %w", err)
+	}
+	doc.base, err = internal.ParseJSON(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing snapshot json: %w", err)
+	}
+
+	return &doc, nil
+}
+
+// MarshalJSON encodes the document, applying any changes made to the snapshot on top of the
+// original json. The document keeps the indentation of the original json.
+func (d *Document) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(d.Snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling snapshot json: %w", err)
+	}
+
+	if d.raw == nil {
+		return data, nil
+	}
+
+	updated, err := internal.ParseJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing snapshot json: %w", err)
+	}
+
+	return internal.MarshalJSONFormat(internal.MergeJSON(d.raw, d.base, updated), d.format)
+}
+
+// Save encodes the document and writes it to the specified path.
+func (d *Document) Save(path string) error {
+	data, err := d.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return internal.WriteFile(path, data)
+}
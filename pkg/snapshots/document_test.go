@@ -0,0 +1,170 @@
+package snapshots_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/peterargue/flow-info/pkg/snapshots"
+)
+
+func TestDocumentRoundTrip(t *testing.T) {
+	snapshot := buildSnapshot(t, 3)
+
+	compact, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indented, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{
+		"compact":          compact,
+		"indented":         indented,
+		"trailing newline": append(indented, '\n'),
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "root-protocol-state-snapshot.json")
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			doc, err := snapshots.LoadDocument(path)
+			if err != nil {
+				t.Fatalf("error loading document: %v", err)
+			}
+
+			saved := filepath.Join(dir, "saved.json")
+			if err := doc.Save(saved); err != nil {
+				t.Fatalf("error saving document: %v", err)
+			}
+
+			got, err := os.ReadFile(saved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Error("saved document is not byte-identical to the original")
+			}
+		})
+	}
+}
+
+func TestDocumentKeepsFieldsOfRemovedIdentity(t *testing.T) {
+	snapshot := buildSnapshot(t, 1)
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// add a field the snapshot does not model to each identity, tagged with its node ID
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	entries := raw["SealingSegment"].(map[string]interface{})["ProtocolStateEntries"].(map[string]interface{})
+	for _, entry := range entries {
+		table := entry.(map[string]interface{})["EpochEntry"].(map[string]interface{})["CurrentEpochIdentityTable"].([]interface{})
+		for _, identity := range table {
+			identity := identity.(map[string]interface{})
+			identity["Unmodelled"] = identity["NodeID"]
+		}
+	}
+	data, err = json.Marshal(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := snapshots.DecodeDocument(data)
+	if err != nil {
+		t.Fatalf("error decoding document: %v", err)
+	}
+
+	// remove the first identity
+	var remaining int
+	for id, state := range doc.SealingSegment.ProtocolStateEntries {
+		state.EpochEntry.CurrentEpochIdentityTable = state.EpochEntry.CurrentEpochIdentityTable[1:]
+		doc.SealingSegment.ProtocolStateEntries[id] = state
+		remaining = len(state.EpochEntry.CurrentEpochIdentityTable)
+	}
+
+	encoded, err := doc.MarshalJSON()
+	if err != nil {
+		t.Fatalf("error encoding document: %v", err)
+	}
+
+	var result struct {
+		SealingSegment struct {
+			ProtocolStateEntries map[string]struct {
+				EpochEntry struct {
+					CurrentEpochIdentityTable []struct {
+						NodeID     string
+						Unmodelled string
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range result.SealingSegment.ProtocolStateEntries {
+		table := entry.EpochEntry.CurrentEpochIdentityTable
+		if len(table) != remaining {
+			t.Fatalf("expected %d identities, got %d", remaining, len(table))
+		}
+		for _, identity := range table {
+			if identity.Unmodelled != identity.NodeID {
+				t.Errorf("identity %s has the unmodelled field of %s", identity.NodeID, identity.Unmodelled)
+			}
+		}
+	}
+}
+
+func TestDocumentDeletesEntries(t *testing.T) {
+	snapshot := buildSnapshot(t, 3)
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := snapshots.DecodeDocument(data)
+	if err != nil {
+		t.Fatalf("error decoding document: %v", err)
+	}
+
+	// delete a map entry and clear an omitempty field
+	deleted := doc.SealingSegment.Blocks[2].Header.ID
+	delete(doc.SealingSegment.LatestSeals, deleted)
+	for id, state := range doc.SealingSegment.ProtocolStateEntries {
+		state.EpochEntry.CurrentEpochCommit.DKGIndexMap = nil
+		doc.SealingSegment.ProtocolStateEntries[id] = state
+	}
+
+	encoded, err := doc.MarshalJSON()
+	if err != nil {
+		t.Fatalf("error encoding document: %v", err)
+	}
+
+	decoded, err := snapshots.DecodeDocument(encoded)
+	if err != nil {
+		t.Fatalf("error decoding document: %v", err)
+	}
+	if _, ok := decoded.SealingSegment.LatestSeals[deleted]; ok {
+		t.Errorf("expected the latest seal for %s to be deleted", deleted)
+	}
+	if len(decoded.SealingSegment.LatestSeals) != 2 {
+		t.Errorf("expected 2 latest seals, got %d", len(decoded.SealingSegment.LatestSeals))
+	}
+	if commit := decoded.SealingSegment.ProtocolStateEntry().EpochEntry.CurrentEpochCommit; commit.DKGIndexMap != nil {
+		t.Errorf("expected the DKG index map to be deleted, got %v", commit.DKGIndexMap)
+	}
+}
@@ -0,0 +1,45 @@
+package snapshots
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/onflow/crypto/hash"
+	"github.com/onflow/go-ethereum/rlp"
+)
+
+// identifierLen is the length of a flow identifier in bytes.
+const identifierLen = 32
+
+// identifier is a flow identifier, encoded the same way as flow-go's flow.Identifier.
+type identifier [identifierLen]byte
+
+// decodeIdentifier decodes a hex encoded flow identifier.
+func decodeIdentifier(id string) (identifier, error) {
+	var result identifier
+
+	data, err := hex.DecodeString(id)
+	if err != nil {
+		return result, fmt.Errorf("invalid identifier %s: %w", id, err)
+	}
+	if len(data) != identifierLen {
+		return result, fmt.Errorf("invalid identifier %s: expected %d bytes, got %d", id, identifierLen, len(data))
+	}
+
+	copy(result[:], data)
+	return result, nil
+}
+
+// makeID computes a flow identifier from the RLP fingerprint of the entity, the same way as
+// flow-go's flow.MakeID.
+func makeID(entity interface{}) (identifier, error) {
+	var result identifier
+
+	fingerprint, err := rlp.EncodeToBytes(entity)
+	if err != nil {
+		return result, fmt.Errorf("error encoding fingerprint: %w", err)
+	}
+
+	copy(result[:], hash.NewSHA3_256().ComputeHash(fingerprint))
+	return result, nil
+}
@@ -0,0 +1,260 @@
+package snapshots
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// The ID computations below reproduce flow-go's RLP fingerprints for each entity. IDs are the
+// SHA3-256 hash of the fingerprint.
+
+// ComputeID recomputes the block ID from the header fields.
+func (h Header) ComputeID() (string, error) {
+	if h.LastViewTC != nil {
+		return "", fmt.Errorf("computing IDs for headers with a timeout certificate is not supported")
+	}
+
+	var err error
+	var body struct {
+		ChainID            string
+		ParentID           identifier
+		Height             uint64
+		PayloadHash        identifier
+		Timestamp          uint64
+		View               uint64
+		ParentView         uint64
+		ParentVoterIndices []byte
+		ParentVoterSigData []byte
+		ProposerID         identifier
+		LastViewTCID       identifier
+	}
+
+	body.ChainID = h.ChainID
+	body.Height = h.Height
+	body.View = h.View
+	body.ParentView = h.ParentView
+
+	if body.ParentID, err = decodeIdentifier(h.ParentID); err != nil {
+		return "", fmt.Errorf("invalid parent ID: %w", err)
+	}
+	if body.PayloadHash, err = decodeIdentifier(h.PayloadHash); err != nil {
+		return "", fmt.Errorf("invalid payload hash: %w", err)
+	}
+	if body.ProposerID, err = decodeIdentifier(h.ProposerID); err != nil {
+		return "", fmt.Errorf("invalid proposer ID: %w", err)
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, h.Timestamp)
+	if err != nil {
+		return "", fmt.Errorf("invalid timestamp: %w", err)
+	}
+	body.Timestamp = uint64(timestamp.UnixNano())
+
+	if body.ParentVoterIndices, err = decodeBytes(h.ParentVoterIndices); err != nil {
+		return "", fmt.Errorf("invalid parent voter indices: %w", err)
+	}
+	if body.ParentVoterSigData, err = decodeBytes(h.ParentVoterSigData); err != nil {
+		return "", fmt.Errorf("invalid parent voter sig data: %w", err)
+	}
+
+	return computeID(body)
+}
+
+// ComputeID recomputes the seal ID from the seal fields.
+func (s Seal) ComputeID() (string, error) {
+	var err error
+	var body struct {
+		BlockID                identifier
+		ResultID               identifier
+		FinalState             identifier
+		AggregatedApprovalSigs []struct {
+			VerifierSignatures [][]byte
+			SignerIDs          []identifier
+		}
+	}
+
+	if body.BlockID, err = decodeIdentifier(s.BlockID); err != nil {
+		return "", fmt.Errorf("invalid block ID: %w", err)
+	}
+	if body.ResultID, err = decodeIdentifier(s.ResultID); err != nil {
+		return "", fmt.Errorf("invalid result ID: %w", err)
+	}
+	if body.FinalState, err = decodeIdentifier(s.FinalState); err != nil {
+		return "", fmt.Errorf("invalid final state: %w", err)
+	}
+
+	body.AggregatedApprovalSigs = make([]struct {
+		VerifierSignatures [][]byte
+		SignerIDs          []identifier
+	}, len(s.AggregatedApprovalSigs))
+	for i, sigs := range s.AggregatedApprovalSigs {
+		body.AggregatedApprovalSigs[i].VerifierSignatures = make([][]byte, len(sigs.VerifierSignatures))
+		for j, sig := range sigs.VerifierSignatures {
+			if body.AggregatedApprovalSigs[i].VerifierSignatures[j], err = decodeBytes(sig); err != nil {
+				return "", fmt.Errorf("invalid verifier signature: %w", err)
+			}
+		}
+
+		body.AggregatedApprovalSigs[i].SignerIDs = make([]identifier, len(sigs.SignerIDs))
+		for j, signerID := range sigs.SignerIDs {
+			if body.AggregatedApprovalSigs[i].SignerIDs[j], err = decodeIdentifier(signerID); err != nil {
+				return "", fmt.Errorf("invalid signer ID: %w", err)
+			}
+		}
+	}
+
+	return computeID(body)
+}
+
+// ComputeID recomputes the receipt ID from the receipt fields.
+func (r Receipt) ComputeID() (string, error) {
+	var err error
+	var body struct {
+		ExecutorID        identifier
+		ResultID          identifier
+		Spocks            [][]byte
+		ExecutorSignature []byte
+	}
+
+	if body.ExecutorID, err = decodeIdentifier(r.ExecutorID); err != nil {
+		return "", fmt.Errorf("invalid executor ID: %w", err)
+	}
+	if body.ResultID, err = decodeIdentifier(r.ResultID); err != nil {
+		return "", fmt.Errorf("invalid result ID: %w", err)
+	}
+
+	body.Spocks = make([][]byte, len(r.Spocks))
+	for i, spock := range r.Spocks {
+		if body.Spocks[i], err = decodeBytes(spock); err != nil {
+			return "", fmt.Errorf("invalid spock: %w", err)
+		}
+	}
+
+	if body.ExecutorSignature, err = decodeBytes(r.ExecutorSignature); err != nil {
+		return "", fmt.Errorf("invalid executor signature: %w", err)
+	}
+
+	return computeID(body)
+}
+
+// ComputeID recomputes the execution result ID from the result fields.
+// Results containing service events are not supported, since the events are not decoded into
+// their typed form.
+func (r ExecutionResult) ComputeID() (string, error) {
+	if len(r.ServiceEvents) > 0 {
+		return "", fmt.Errorf("computing IDs for results with service events is not supported")
+	}
+
+	type chunkBody struct {
+		CollectionIndex      uint64
+		StartState           identifier
+		EventCollection      identifier
+		BlockID              identifier
+		TotalComputationUsed uint64
+		NumberOfTransactions uint64
+	}
+	type chunk struct {
+		ChunkBody chunkBody
+		Index     uint64
+		EndState  identifier
+	}
+
+	var err error
+	var body struct {
+		PreviousResultID identifier
+		BlockID          identifier
+		Chunks           []chunk
+		ServiceEvents    []struct{}
+		ExecutionDataID  identifier
+	}
+
+	if body.PreviousResultID, err = decodeIdentifier(r.PreviousResultID); err != nil {
+		return "", fmt.Errorf("invalid previous result ID: %w", err)
+	}
+	if body.BlockID, err = decodeIdentifier(r.BlockID); err != nil {
+		return "", fmt.Errorf("invalid block ID: %w", err)
+	}
+	if body.ExecutionDataID, err = decodeIdentifier(r.ExecutionDataID); err != nil {
+		return "", fmt.Errorf("invalid execution data ID: %w", err)
+	}
+
+	body.Chunks = make([]chunk, len(r.Chunks))
+	for i, c := range r.Chunks {
+		body.Chunks[i] = chunk{
+			ChunkBody: chunkBody{
+				CollectionIndex:      c.CollectionIndex,
+				TotalComputationUsed: c.TotalComputationUsed,
+				NumberOfTransactions: c.NumberOfTransactions,
+			},
+			Index: c.Index,
+		}
+		if body.Chunks[i].ChunkBody.StartState, err = decodeIdentifier(c.StartState); err != nil {
+			return "", fmt.Errorf("invalid chunk %d start state: %w", i, err)
+		}
+		if body.Chunks[i].ChunkBody.EventCollection, err = decodeIdentifier(c.EventCollection); err != nil {
+			return "", fmt.Errorf("invalid chunk %d event collection: %w", i, err)
+		}
+		if body.Chunks[i].ChunkBody.BlockID, err = decodeIdentifier(c.BlockID); err != nil {
+			return "", fmt.Errorf("invalid chunk %d block ID: %w", i, err)
+		}
+		if body.Chunks[i].EndState, err = decodeIdentifier(c.EndState); err != nil {
+			return "", fmt.Errorf("invalid chunk %d end state: %w", i, err)
+		}
+	}
+
+	return computeID(body)
+}
+
+// ValidateIDs recomputes the IDs of all headers, seals, receipts and execution results, and the
+// hash of each block payload, in the snapshot's sealing segment and checks them against the
+// stored values. It returns all mismatches found, or nil if all IDs match. Entities whose IDs
+// cannot be recomputed, like results with service events, are reported as violations since their
+// IDs could not be checked.
+func ValidateIDs(snapshot *Snapshot) []error {
+	var violations []error
+	check := func(kind, storedID string, computeID func() (string, error)) {
+		id, err := computeID()
+		if err != nil {
+			violations = append(violations, fmt.Errorf("%s %s: cannot recompute ID: %w", kind, storedID, err))
+			return
+		}
+		if id != storedID {
+			violations = append(violations, fmt.Errorf("%s %s: computed ID %s does not match", kind, storedID, id))
+		}
+	}
+
+	segment := snapshot.SealingSegment
+	blocks := append(append([]Block{}, segment.ExtraBlocks...), segment.Blocks...)
+	for _, block := range blocks {
+		check("block", block.Header.ID, block.Header.ComputeID)
+		check("payload", block.Header.PayloadHash, block.Payload.ComputeHash)
+		for _, seal := range block.Payload.Seals {
+			check("seal", seal.ID, seal.ComputeID)
+		}
+		for _, receipt := range block.Payload.Receipts {
+			check("receipt", receipt.ID, receipt.ComputeID)
+		}
+		for _, result := range block.Payload.Results {
+			check("execution result", result.ID, result.ComputeID)
+		}
+	}
+
+	for _, result := range segment.ExecutionResults {
+		check("execution result", result.ID, result.ComputeID)
+	}
+
+	if segment.FirstSeal.ID != "" {
+		check("seal", segment.FirstSeal.ID, Seal(segment.FirstSeal).ComputeID)
+	}
+
+	return violations
+}
+
+func computeID(entity interface{}) (string, error) {
+	id, err := makeID(entity)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id[:]), nil
+}
@@ -0,0 +1,146 @@
+package snapshots_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/peterargue/flow-info/pkg/snapshots"
+	"github.com/peterargue/flow-info/pkg/snapshots/snapshotstest"
+)
+
+func buildSnapshot(t *testing.T, blocks int) *snapshots.Snapshot {
+	t.Helper()
+
+	snapshot, err := snapshotstest.NewBuilder().WithSeed(7).WithBlocks(blocks).Build()
+	if err != nil {
+		t.Fatalf("error building snapshot: %v", err)
+	}
+	return snapshot
+}
+
+// loadFixtures loads the snapshots in testdata, indexed by file name. The fixtures are snapshots
+// written by flow-go nodes, like a spork's root-protocol-state-snapshot.json, so the IDs, hashes
+// and signatures stored in them were computed by flow-go. The test is skipped if there are none.
+func loadFixtures(t *testing.T) map[string]*snapshots.Snapshot {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Skip("no snapshot fixtures in testdata")
+	}
+
+	fixtures := make(map[string]*snapshots.Snapshot, len(paths))
+	for _, path := range paths {
+		snapshot, err := snapshots.Load(path)
+		if err != nil {
+			t.Fatalf("error loading %s: %v", path, err)
+		}
+		fixtures[filepath.Base(path)] = snapshot
+	}
+	return fixtures
+}
+
+func TestValidateIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(s *snapshots.Snapshot)
+		want   []string
+	}{
+		{
+			name:   "valid",
+			tamper: func(s *snapshots.Snapshot) {},
+		},
+		{
+			name: "tampered block ID",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.Blocks[1].Header.ID = strings.Repeat("ab", 32)
+			},
+			want: []string{"block " + strings.Repeat("ab", 32) + ": computed ID"},
+		},
+		{
+			name: "tampered header field",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.Blocks[0].Header.Height++
+			},
+			want: []string{"computed ID"},
+		},
+		{
+			name: "tampered result ID",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.ExecutionResults[0].ID = strings.Repeat("cd", 32)
+			},
+			want: []string{"execution result " + strings.Repeat("cd", 32) + ": computed ID"},
+		},
+		{
+			name: "tampered first seal",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.FirstSeal.FinalState = strings.Repeat("ef", 32)
+			},
+			want: []string{"seal"},
+		},
+		{
+			name: "tampered payload",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.Blocks[2].Payload.Guarantees = []snapshots.Guarantee{{CollectionID: strings.Repeat("12", 32)}}
+			},
+			want: []string{"payload"},
+		},
+		{
+			name: "result with service events",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.ExecutionResults[0].ServiceEvents = []snapshots.ServiceEvent{{Type: "setup"}}
+			},
+			want: []string{"cannot recompute ID: computing IDs for results with service events is not supported"},
+		},
+		{
+			name: "header with timeout certificate",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.Blocks[1].Header.LastViewTC = map[string]interface{}{"View": 1}
+			},
+			want: []string{"cannot recompute ID: computing IDs for headers with a timeout certificate is not supported"},
+		},
+		{
+			name: "malformed stored ID",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.Blocks[1].Header.ParentID = "not-hex"
+			},
+			want: []string{"cannot recompute ID: invalid parent ID"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			snapshot := buildSnapshot(t, 3)
+			test.tamper(snapshot)
+
+			violations := snapshots.ValidateIDs(snapshot)
+			if len(violations) != len(test.want) {
+				t.Fatalf("expected %d violations, got %d: %v", len(test.want), len(violations), violations)
+			}
+			for i, want := range test.want {
+				if !strings.Contains(violations[i].Error(), want) {
+					t.Errorf("violation %q does not contain %q", violations[i], want)
+				}
+			}
+		})
+	}
+}
+
+// TestValidateIDsFixtures recomputes the block, seal, receipt and result IDs and the payload
+// hashes of snapshots written by flow-go, and checks them against the values flow-go stored.
+func TestValidateIDsFixtures(t *testing.T) {
+	for name, snapshot := range loadFixtures(t) {
+		t.Run(name, func(t *testing.T) {
+			if violations := snapshots.ValidateIDs(snapshot); len(violations) > 0 {
+				t.Errorf("unexpected ID violations: %v", violations)
+			}
+			if violations := snapshots.ValidateSealingSegment(snapshot); len(violations) > 0 {
+				t.Errorf("unexpected sealing segment violations: %v", violations)
+			}
+		})
+	}
+}
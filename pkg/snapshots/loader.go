@@ -14,19 +14,9 @@ import (
 
 // Load loads a snapshot from a local file or url.
 func Load(url string) (*Snapshot, error) {
-	var data []byte
-	var err error
-
-	if strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
-		data, err = internal.Download(url)
-		if err != nil {
-			return nil, fmt.Errorf("error downloading snapshot: %w", err)
-		}
-	} else {
-		data, err = internal.ReadFile(url)
-		if err != nil {
-			return nil, fmt.Errorf("error reading snapshot: %w", err)
-		}
+	data, err := read(url)
+	if err != nil {
+		return nil, err
 	}
 
 	var snapshot Snapshot
@@ -95,3 +85,20 @@ func DownloadByHeightFromAN(
 
 	return internal.WriteFile(path, data)
 }
+
+// read reads snapshot data from a local file or url.
+func read(url string) ([]byte, error) {
+	if strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
+		data, err := internal.Download(url)
+		if err != nil {
+			return nil, fmt.Errorf("error downloading snapshot: %w", err)
+		}
+		return data, nil
+	}
+
+	data, err := internal.ReadFile(url)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,177 @@
+package snapshots
+
+import (
+	"github.com/peterargue/flow-info/pkg/identities"
+)
+
+// ParticipationStatus is a node's participation status within an epoch.
+type ParticipationStatus string
+
+const (
+	ParticipationStatusJoining ParticipationStatus = "EpochParticipationStatusJoining"
+	ParticipationStatusActive  ParticipationStatus = "EpochParticipationStatusActive"
+	ParticipationStatusLeaving ParticipationStatus = "EpochParticipationStatusLeaving"
+	ParticipationStatusEjected ParticipationStatus = "EpochParticipationStatusEjected"
+)
+
+// String returns the short name of the status (e.g. "active").
+func (s ParticipationStatus) String() string {
+	switch s {
+	case ParticipationStatusJoining:
+		return "joining"
+	case ParticipationStatusActive:
+		return "active"
+	case ParticipationStatusLeaving:
+		return "leaving"
+	case ParticipationStatusEjected:
+		return "ejected"
+	}
+	return string(s)
+}
+
+// Participant is a node's identity merged from the epoch setup, the active identities and the
+// identity table.
+type Participant struct {
+	identities.NodeInfo
+	Status        ParticipationStatus
+	Ejected       bool
+	InitialWeight uint64
+	// Weight is the node's current weight. It is zero for nodes that are not active in the epoch.
+	Weight uint64
+}
+
+// IsActive returns true if the node is actively participating in the epoch.
+func (p Participant) IsActive() bool {
+	return p.Status == ParticipationStatusActive && !p.Ejected
+}
+
+// Participants is a list of merged participant identities.
+type Participants []Participant
+
+// ParticipantFilter selects participants from a list.
+type ParticipantFilter func(Participant) bool
+
+// Filter returns the participants that match all the filters.
+func (l Participants) Filter(filters ...ParticipantFilter) Participants {
+	var result Participants
+	for _, p := range l {
+		matched := true
+		for _, filter := range filters {
+			if !filter(p) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// HasStatus matches participants with any of the statuses.
+func HasStatus(statuses ...ParticipationStatus) ParticipantFilter {
+	return func(p Participant) bool {
+		for _, status := range statuses {
+			if p.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IsEjected matches participants that were ejected.
+func IsEjected() ParticipantFilter {
+	return func(p Participant) bool {
+		return p.Ejected
+	}
+}
+
+// MatchIdentity adapts an identity filter to match participants by their node info.
+func MatchIdentity(filter identities.Filter) ParticipantFilter {
+	return func(p Participant) bool {
+		return filter(p.NodeInfo)
+	}
+}
+
+// Active returns the participants that are actively participating in the epoch.
+func (l Participants) Active() Participants {
+	return l.Filter(Participant.IsActive)
+}
+
+// ByStatus returns the participants with the given status.
+func (l Participants) ByStatus(status ParticipationStatus) Participants {
+	return l.Filter(HasStatus(status))
+}
+
+// Identities returns the node info for each participant.
+func (l Participants) Identities() identities.IdentityList {
+	list := make(identities.IdentityList, len(l))
+	for i, p := range l {
+		list[i] = p.NodeInfo
+	}
+	return list
+}
+
+// CurrentParticipants returns the merged identities of all nodes in the current epoch.
+func (s Snapshot) CurrentParticipants() Participants {
+	state := s.SealingSegment.ProtocolStateEntry()
+	return state.EpochEntry.CurrentEpochParticipants()
+}
+
+// CurrentEpochParticipants returns the merged identities of all nodes in the current epoch. This
+// includes nodes joining in the next epoch and leaving from the previous epoch, as well as nodes
+// that were ejected.
+func (e *EpochEntry) CurrentEpochParticipants() Participants {
+	ejected := make(map[string]bool, len(e.CurrentEpoch.ActiveIdentities))
+	for _, identity := range e.CurrentEpoch.ActiveIdentities {
+		ejected[identity.NodeID] = identity.Ejected
+	}
+
+	table := e.CurrentEpochIdentityTable
+	if len(table) == 0 {
+		// older snapshots may not include the identity table, so fall back to the setup participants
+		table = e.CurrentEpochSetup.Participants
+	}
+
+	inCurrent := participantSet(e.CurrentEpochSetup.Participants)
+	inNext := participantSet(e.NextEpochSetup.Participants)
+
+	participants := make(Participants, 0, len(table))
+	for _, identity := range table {
+		p := Participant{
+			NodeInfo:      identity.Info(),
+			Status:        identity.ParticipationStatus,
+			Ejected:       ejected[identity.NodeID],
+			InitialWeight: identity.Info().Stake,
+		}
+
+		switch {
+		case p.Ejected:
+			p.Status = ParticipationStatusEjected
+		case p.Status != "":
+		case inCurrent[identity.NodeID]:
+			p.Status = ParticipationStatusActive
+		case inNext[identity.NodeID]:
+			p.Status = ParticipationStatusJoining
+		default:
+			p.Status = ParticipationStatusLeaving
+		}
+
+		if p.IsActive() {
+			p.Weight = p.InitialWeight
+		}
+
+		participants = append(participants, p)
+	}
+	return participants
+}
+
+func participantSet(participants []Identity) map[string]bool {
+	set := make(map[string]bool, len(participants))
+	for _, identity := range participants {
+		set[identity.NodeID] = true
+	}
+	return set
+}
@@ -0,0 +1,115 @@
+package snapshots_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/snapshots"
+)
+
+// participantsSnapshot returns a snapshot whose first node is ejected, second is joining and third
+// is leaving. The other nodes are active.
+func participantsSnapshot(t *testing.T) (*snapshots.Snapshot, []string) {
+	t.Helper()
+
+	snapshot := buildSnapshot(t, 1)
+	var nodeIDs []string
+	updateEntry(snapshot, func(e *snapshots.EpochEntry) {
+		table := e.CurrentEpochIdentityTable
+		for i := range table {
+			table[i].ParticipationStatus = ""
+			nodeIDs = append(nodeIDs, table[i].NodeID)
+		}
+
+		e.CurrentEpoch.ActiveIdentities[0].Ejected = true
+		e.NextEpochSetup.Participants = []snapshots.Identity{table[1]}
+		e.CurrentEpochSetup.Participants = append([]snapshots.Identity{table[0]}, e.CurrentEpochSetup.Participants[3:]...)
+	})
+	return snapshot, nodeIDs
+}
+
+func TestCurrentParticipants(t *testing.T) {
+	snapshot, nodeIDs := participantsSnapshot(t)
+	participants := snapshot.CurrentParticipants()
+
+	if len(participants) != len(nodeIDs) {
+		t.Fatalf("expected %d participants, got %d", len(nodeIDs), len(participants))
+	}
+
+	for i, p := range participants {
+		want := snapshots.ParticipationStatusActive
+		switch i {
+		case 0:
+			want = snapshots.ParticipationStatusEjected
+		case 1:
+			want = snapshots.ParticipationStatusJoining
+		case 2:
+			want = snapshots.ParticipationStatusLeaving
+		}
+		if p.Status != want {
+			t.Errorf("participant %d: expected status %s, got %s", i, want, p.Status)
+		}
+		if p.InitialWeight == 0 {
+			t.Errorf("participant %d: expected an initial weight", i)
+		}
+		if active := p.IsActive(); active != (i > 2) || (p.Weight != 0) != active {
+			t.Errorf("participant %d: unexpected active %t with weight %d", i, active, p.Weight)
+		}
+	}
+	if !participants[0].Ejected {
+		t.Error("expected the first participant to be ejected")
+	}
+}
+
+func TestParticipantFilters(t *testing.T) {
+	snapshot, nodeIDs := participantsSnapshot(t)
+	participants := snapshot.CurrentParticipants()
+
+	ids := func(l snapshots.Participants) []string {
+		return l.Identities().NodeIDs()
+	}
+
+	if got := ids(participants.Active()); !reflect.DeepEqual(got, nodeIDs[3:]) {
+		t.Errorf("active: expected %v, got %v", nodeIDs[3:], got)
+	}
+	if got := ids(participants.ByStatus(snapshots.ParticipationStatusJoining)); !reflect.DeepEqual(got, nodeIDs[1:2]) {
+		t.Errorf("joining: expected %v, got %v", nodeIDs[1:2], got)
+	}
+	if got := ids(participants.Filter(snapshots.IsEjected())); !reflect.DeepEqual(got, nodeIDs[:1]) {
+		t.Errorf("ejected: expected %v, got %v", nodeIDs[:1], got)
+	}
+
+	leavingOrJoining := participants.Filter(snapshots.HasStatus(snapshots.ParticipationStatusJoining, snapshots.ParticipationStatusLeaving))
+	if got := ids(leavingOrJoining); !reflect.DeepEqual(got, nodeIDs[1:3]) {
+		t.Errorf("joining or leaving: expected %v, got %v", nodeIDs[1:3], got)
+	}
+
+	consensus := participants.Filter(
+		snapshots.MatchIdentity(identities.HasRole(identities.RoleConsensus)),
+		snapshots.Participant.IsActive,
+	)
+	for _, p := range consensus {
+		if p.Role != identities.RoleConsensus || !p.IsActive() {
+			t.Errorf("unexpected participant %+v", p)
+		}
+	}
+	want := participants.Active().Identities().Filter(identities.HasRole(identities.RoleConsensus)).NodeIDs()
+	if got := ids(consensus); !reflect.DeepEqual(got, want) {
+		t.Errorf("active consensus: expected %v, got %v", want, got)
+	}
+}
+
+func TestParticipationStatusString(t *testing.T) {
+	for status, want := range map[snapshots.ParticipationStatus]string{
+		snapshots.ParticipationStatusJoining: "joining",
+		snapshots.ParticipationStatusActive:  "active",
+		snapshots.ParticipationStatusLeaving: "leaving",
+		snapshots.ParticipationStatusEjected: "ejected",
+		"EpochParticipationStatusUnknown":    "EpochParticipationStatusUnknown",
+	} {
+		if got := status.String(); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package snapshots
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/onflow/crypto/hash"
+	"golang.org/x/crypto/blake2b"
+)
+
+// The node tags are used as blake2b keys when hashing merkle tree nodes, the same way as flow-go's
+// module/merkle package.
+var (
+	leafNodeTag  = []byte{0}
+	shortNodeTag = []byte{1}
+	fullNodeTag  = []byte{2}
+)
+
+// ComputeHash recomputes the payload hash stored in the block header. The hash is computed from the
+// stored IDs of the payload's entities, which are checked separately by ValidateIDs.
+func (p Payload) ComputeHash() (string, error) {
+	// a guarantee's ID is its collection ID
+	guarantees := make([]string, len(p.Guarantees))
+	for i, guarantee := range p.Guarantees {
+		guarantees[i] = guarantee.CollectionID
+	}
+	seals := make([]string, len(p.Seals))
+	for i, seal := range p.Seals {
+		seals[i] = seal.ID
+	}
+	receipts := make([]string, len(p.Receipts))
+	for i, receipt := range p.Receipts {
+		receipts[i] = receipt.ID
+	}
+	results := make([]string, len(p.Results))
+	for i, result := range p.Results {
+		results[i] = result.ID
+	}
+
+	hasher := hash.NewSHA3_256()
+	for _, list := range [][]string{guarantees, seals, receipts, results} {
+		root, err := merkleRoot(list)
+		if err != nil {
+			return "", err
+		}
+		_, _ = hasher.Write(root[:])
+	}
+
+	// payloads from before the protocol state was added have no protocol state ID
+	if p.ProtocolStateID != "" {
+		id, err := decodeIdentifier(p.ProtocolStateID)
+		if err != nil {
+			return "", fmt.Errorf("invalid protocol state ID: %w", err)
+		}
+		_, _ = hasher.Write(id[:])
+	}
+
+	return hex.EncodeToString(hasher.SumHash()), nil
+}
+
+// merkleRoot computes the same root as flow-go's flow.MerkleRoot. Each ID is inserted into a
+// patricia merkle tree with its index in the list as the value.
+func merkleRoot(list []string) (identifier, error) {
+	var root identifier
+
+	values := make(map[identifier]uint64, len(list))
+	for i, id := range list {
+		key, err := decodeIdentifier(id)
+		if err != nil {
+			return root, err
+		}
+		// duplicate keys overwrite the earlier value
+		values[key] = uint64(i)
+	}
+
+	if len(values) == 0 {
+		h, _ := blake2b.New256(nil)
+		copy(root[:], h.Sum(nil))
+		return root, nil
+	}
+
+	keys := make([]identifier, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i][:], keys[j][:]) < 0
+	})
+
+	copy(root[:], merkleNode(keys, values, 0))
+	return root, nil
+}
+
+// merkleNode returns the hash of the node for the sorted keys, which all share their first depth
+// bits. Paths without branches are compressed into short nodes.
+func merkleNode(keys []identifier, values map[identifier]uint64, depth int) []byte {
+	const keyBits = identifierLen * 8
+
+	// find where the keys branch. A single key runs to the leaf.
+	split := keyBits
+	if len(keys) > 1 {
+		split = depth
+		for readBit(keys[0][:], split) == readBit(keys[len(keys)-1][:], split) {
+			split++
+		}
+	}
+
+	var child []byte
+	if split == keyBits {
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, values[keys[0]])
+
+		h, _ := blake2b.New256(leafNodeTag)
+		_, _ = h.Write(value)
+		child = h.Sum(nil)
+	} else {
+		// keys are sorted, so the first key with the bit set starts the right branch
+		right := sort.Search(len(keys), func(i int) bool {
+			return readBit(keys[i][:], split)
+		})
+
+		h, _ := blake2b.New256(fullNodeTag)
+		_, _ = h.Write(merkleNode(keys[:right], values, split+1))
+		_, _ = h.Write(merkleNode(keys[right:], values, split+1))
+		child = h.Sum(nil)
+	}
+
+	count := split - depth
+	if count == 0 {
+		return child
+	}
+
+	path := make([]byte, bitVectorLen(count))
+	for i := 0; i < count; i++ {
+		if readBit(keys[0][:], depth+i) {
+			path[i/8] |= 1 << (7 - i%8)
+		}
+	}
+
+	var length [2]byte
+	binary.BigEndian.PutUint16(length[:], uint16(count))
+
+	h, _ := blake2b.New256(shortNodeTag)
+	_, _ = h.Write(length[:])
+	_, _ = h.Write(path)
+	_, _ = h.Write(child)
+	return h.Sum(nil)
+}
@@ -0,0 +1,182 @@
+package snapshots
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+)
+
+// signerIndicesChecksumLen is the length of the committee checksum prefixed to signer indices.
+const signerIndicesChecksumLen = 4
+
+// DecodeSignerIndices decodes flow's signer indices encoding into the list of committee members that
+// signed. The committee must be in canonical order. The encoding is a checksum of the committee's
+// node IDs, followed by a bit vector with one bit per committee member.
+func DecodeSignerIndices(committee identities.IdentityList, signerIndices []byte) (identities.IdentityList, error) {
+	if len(signerIndices) < signerIndicesChecksumLen {
+		return nil, fmt.Errorf("signer indices too short: %d bytes", len(signerIndices))
+	}
+
+	checksum, err := committeeChecksum(committee)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(checksum, signerIndices[:signerIndicesChecksumLen]) {
+		return nil, fmt.Errorf("signer indices checksum mismatch: expected %x, got %x",
+			checksum, signerIndices[:signerIndicesChecksumLen])
+	}
+
+	bitVector := signerIndices[signerIndicesChecksumLen:]
+	if len(bitVector) != bitVectorLen(len(committee)) {
+		return nil, fmt.Errorf("invalid signer indices length: expected %d bytes for %d committee members, got %d",
+			bitVectorLen(len(committee)), len(committee), len(bitVector))
+	}
+
+	// padding bits after the last committee member must be zero
+	for i := len(committee); i < len(bitVector)*8; i++ {
+		if readBit(bitVector, i) {
+			return nil, fmt.Errorf("invalid signer indices: padding bit %d is set", i)
+		}
+	}
+
+	var signers identities.IdentityList
+	for i, member := range committee {
+		if readBit(bitVector, i) {
+			signers = append(signers, member)
+		}
+	}
+	return signers, nil
+}
+
+// EncodeSignerIndices encodes the signers using flow's signer indices encoding. The committee must
+// be in canonical order, and all signers must be members of the committee.
+func EncodeSignerIndices(committee identities.IdentityList, signers identities.IdentityList) ([]byte, error) {
+	checksum, err := committeeChecksum(committee)
+	if err != nil {
+		return nil, err
+	}
+
+	indices := make(map[string]int, len(committee))
+	for i, member := range committee {
+		indices[member.NodeID] = i
+	}
+
+	bitVector := make([]byte, bitVectorLen(len(committee)))
+	for _, signer := range signers {
+		i, ok := indices[signer.NodeID]
+		if !ok {
+			return nil, fmt.Errorf("signer %s is not a member of the committee", signer.NodeID)
+		}
+		bitVector[i/8] |= 1 << (7 - i%8)
+	}
+
+	return append(checksum, bitVector...), nil
+}
+
+// ConsensusCommittee returns the consensus nodes for the epoch in canonical order.
+func (e EpochSetup) ConsensusCommittee() identities.IdentityList {
+	return e.Identities().ByRole(identities.RoleConsensus).Sort()
+}
+
+// ClusterCommittees returns the collection clusters for the epoch with members in canonical order.
+func (e EpochSetup) ClusterCommittees() []identities.IdentityList {
+	clusters := e.Clusters()
+	for i, cluster := range clusters {
+		clusters[i] = cluster.Sort()
+	}
+	return clusters
+}
+
+// Signers returns the consensus nodes that signed the QC.
+func (qc QuorumCertificate) Signers(setup EpochSetup) (identities.IdentityList, error) {
+	indices, err := decodeBytes(qc.SignerIndices)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signer indices: %w", err)
+	}
+	return DecodeSignerIndices(setup.ConsensusCommittee(), indices)
+}
+
+// ParentVoters returns the consensus nodes that voted for the block's parent.
+func (h Header) ParentVoters(setup EpochSetup) (identities.IdentityList, error) {
+	indices, err := decodeBytes(h.ParentVoterIndices)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding parent voter indices: %w", err)
+	}
+	return DecodeSignerIndices(setup.ConsensusCommittee(), indices)
+}
+
+// Guarantors returns the collection nodes that guaranteed the collection, and the index of their
+// cluster. The cluster is identified by the committee checksum in the signer indices.
+func (g Guarantee) Guarantors(setup EpochSetup) (identities.IdentityList, int, error) {
+	indices, err := decodeBytes(g.SignerIndices)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error decoding signer indices: %w", err)
+	}
+
+	if len(indices) < signerIndicesChecksumLen {
+		return nil, 0, fmt.Errorf("signer indices too short: %d bytes", len(indices))
+	}
+
+	for i, cluster := range setup.ClusterCommittees() {
+		checksum, err := committeeChecksum(cluster)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error computing checksum for cluster %d: %w", i, err)
+		}
+		if !bytes.Equal(checksum, indices[:signerIndicesChecksumLen]) {
+			continue
+		}
+
+		guarantors, err := DecodeSignerIndices(cluster, indices)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error decoding signer indices for cluster %d: %w", i, err)
+		}
+		return guarantors, i, nil
+	}
+
+	return nil, 0, fmt.Errorf("no cluster matches signer indices checksum %x", indices[:signerIndicesChecksumLen])
+}
+
+// RootQCSigners returns the consensus nodes that signed the snapshot's root QC.
+func (s Snapshot) RootQCSigners() (identities.IdentityList, error) {
+	return s.QuorumCertificate.Signers(s.CurrentEpochSetup())
+}
+
+// committeeChecksum returns the CRC32 checksum of the concatenated node IDs of the committee.
+func committeeChecksum(committee identities.IdentityList) ([]byte, error) {
+	checksum := make([]byte, signerIndicesChecksumLen)
+	if len(committee) == 0 {
+		return checksum, nil
+	}
+
+	encoded := make([]byte, 0, len(committee)*32)
+	for _, member := range committee {
+		id, err := hex.DecodeString(member.NodeID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node ID %s: %w", member.NodeID, err)
+		}
+		encoded = append(encoded, id...)
+	}
+
+	binary.BigEndian.PutUint32(checksum, crc32.ChecksumIEEE(encoded))
+	return checksum, nil
+}
+
+func bitVectorLen(bits int) int {
+	return (bits + 7) / 8
+}
+
+// readBit returns the bit at index i, where bits are ordered from the most significant bit of each byte.
+func readBit(vector []byte, i int) bool {
+	return vector[i/8]&(1<<(7-i%8)) != 0
+}
+
+// decodeBytes decodes a byte slice field from snapshot json, which is base64 encoded.
+func decodeBytes(data string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(data)
+}
@@ -22,6 +22,13 @@ func (s Snapshot) NextEpochSetup() EpochSetup {
 	return state.EpochEntry.NextEpochSetup
 }
 
+// CurrentEpochFinalView returns the final view of the current epoch, including any extensions
+// added while in epoch fallback mode.
+func (s Snapshot) CurrentEpochFinalView() uint64 {
+	state := s.SealingSegment.ProtocolStateEntry()
+	return state.EpochEntry.CurrentEpochFinalView()
+}
+
 // SealingSegment struct
 type SealingSegment struct {
 	Blocks               []Block                       `json:"Blocks"`
@@ -144,18 +151,60 @@ type KVStore struct {
 }
 
 type Epoch struct {
-	SetupID          string `json:"SetupID"`
-	CommitID         string `json:"CommitID"`
-	ActiveIdentities []struct {
-		NodeID  string `json:"NodeID"`
-		Ejected bool   `json:"Ejected"`
-	} `json:"ActiveIdentities"`
-	EpochExtensions interface{} `json:"EpochExtensions"`
+	SetupID          string            `json:"SetupID"`
+	CommitID         string            `json:"CommitID"`
+	ActiveIdentities []DynamicIdentity `json:"ActiveIdentities"`
+	EpochExtensions  []EpochExtension  `json:"EpochExtensions"`
+}
+
+// DynamicIdentity contains the parts of a node's identity that may change during an epoch.
+type DynamicIdentity struct {
+	NodeID  string `json:"NodeID"`
+	Ejected bool   `json:"Ejected"`
+}
+
+// EpochExtension is a range of views appended to an epoch while the network is in epoch fallback mode.
+type EpochExtension struct {
+	FirstView uint64 `json:"FirstView"`
+	FinalView uint64 `json:"FinalView"`
+}
+
+// Extended returns true if the epoch has been extended by epoch fallback mode.
+func (e Epoch) Extended() bool {
+	return len(e.EpochExtensions) > 0
+}
+
+// EffectiveFinalView returns the final view of the epoch, taking into account any extensions.
+// setupFinalView is the FinalView from the epoch's EpochSetup event.
+func (e Epoch) EffectiveFinalView(setupFinalView uint64) uint64 {
+	finalView := setupFinalView
+	for _, ext := range e.EpochExtensions {
+		if ext.FinalView > finalView {
+			finalView = ext.FinalView
+		}
+	}
+	return finalView
 }
 
 type Identity struct {
 	identities.NodeInfo
-	ParticipationStatus string `json:"ParticipationStatus,omitempty"`
+	InitialWeight uint64 `json:"InitialWeight,omitempty"`
+	// Weight is the node's stake in snapshots written before InitialWeight was introduced.
+	Weight              uint64              `json:"Weight,omitempty"`
+	ParticipationStatus ParticipationStatus `json:"ParticipationStatus,omitempty"`
+}
+
+// Info returns the node info for the identity. Newer snapshots record the node's stake as
+// InitialWeight, and older ones as Weight or Stake, so the first one set is copied to Stake.
+func (i Identity) Info() identities.NodeInfo {
+	info := i.NodeInfo
+	switch {
+	case i.InitialWeight > 0:
+		info.Stake = i.InitialWeight
+	case i.Weight > 0:
+		info.Stake = i.Weight
+	}
+	return info
 }
 
 type EpochSetup struct {
@@ -172,10 +221,21 @@ type EpochSetup struct {
 	TargetEndTime      uint64     `json:"TargetEndTime"`
 }
 
+// EffectiveFinalView returns the final view of the epoch, taking into account the extensions
+// recorded for it in the protocol state.
+func (e EpochSetup) EffectiveFinalView(epoch Epoch) uint64 {
+	return epoch.EffectiveFinalView(e.FinalView)
+}
+
+// ContainsView returns true if the view falls within the epoch, including any extensions.
+func (e EpochSetup) ContainsView(epoch Epoch, view uint64) bool {
+	return view >= e.FirstView && view <= e.EffectiveFinalView(epoch)
+}
+
 func (e EpochSetup) Identities() identities.IdentityList {
 	list := make(identities.IdentityList, len(e.Participants))
 	for i, v := range e.Participants {
-		list[i] = v.NodeInfo
+		list[i] = v.Info()
 	}
 	return list
 }
@@ -183,7 +243,7 @@ func (e EpochSetup) Identities() identities.IdentityList {
 func (e EpochSetup) Clusters() []identities.IdentityList {
 	participants := make(map[string]identities.NodeInfo, len(e.Participants))
 	for _, identity := range e.Participants {
-		participants[identity.NodeID] = identity.NodeInfo
+		participants[identity.NodeID] = identity.Info()
 	}
 
 	clusters := make([]identities.IdentityList, len(e.Assignments))
@@ -202,13 +262,17 @@ func (e EpochSetup) Clusters() []identities.IdentityList {
 }
 
 type EpochCommit struct {
-	Counter    uint64 `json:"Counter"`
-	ClusterQCs []struct {
-		SigData  string   `json:"SigData"`
-		VoterIDs []string `json:"VoterIDs"`
-	} `json:"ClusterQCs"`
-	DKGGroupKey        string   `json:"DKGGroupKey"`
-	DKGParticipantKeys []string `json:"DKGParticipantKeys"`
+	Counter            uint64         `json:"Counter"`
+	ClusterQCs         []ClusterQC    `json:"ClusterQCs"`
+	DKGGroupKey        string         `json:"DKGGroupKey"`
+	DKGParticipantKeys []string       `json:"DKGParticipantKeys"`
+	DKGIndexMap        map[string]int `json:"DKGIndexMap,omitempty"`
+}
+
+// ClusterQC is the root QC for a collection cluster.
+type ClusterQC struct {
+	SigData  string   `json:"SigData"`
+	VoterIDs []string `json:"VoterIDs"`
 }
 
 // EpochEntry struct
@@ -227,10 +291,26 @@ type EpochEntry struct {
 	NextEpochIdentityTable    []Identity  `json:"NextEpochIdentityTable"`
 }
 
+// InEpochFallback returns true if the network is in epoch fallback mode, or the current epoch
+// has been extended by it.
+func (e *EpochEntry) InEpochFallback() bool {
+	return e.EpochFallbackTriggered || e.CurrentEpoch.Extended()
+}
+
+// CurrentEpochFinalView returns the final view of the current epoch, including any extensions.
+func (e *EpochEntry) CurrentEpochFinalView() uint64 {
+	return e.CurrentEpochSetup.EffectiveFinalView(e.CurrentEpoch)
+}
+
+// NextEpochFinalView returns the final view of the next epoch, including any extensions.
+func (e *EpochEntry) NextEpochFinalView() uint64 {
+	return e.NextEpochSetup.EffectiveFinalView(e.NextEpoch)
+}
+
 func (e *EpochEntry) CurrentEpochInitialIdentities() identities.IdentityList {
 	list := make(identities.IdentityList, len(e.CurrentEpochIdentityTable))
 	for i, v := range e.CurrentEpochIdentityTable {
-		list[i] = v.NodeInfo
+		list[i] = v.Info()
 	}
 	return list
 }
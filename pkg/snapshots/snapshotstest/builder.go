@@ -0,0 +1,578 @@
+// Package snapshotstest provides a builder for synthesizing protocol state snapshots for use in
+// tests. Built snapshots pass this module's ID, sealing segment and signature checks, but they are
+// not snapshots a flow-go node would accept: the protocol state, epoch setup and commit IDs and the
+// previous result ID are random, the KV store is empty and the DKG group key is not the output of
+// a real DKG.
+package snapshotstest
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/onflow/crypto"
+	"github.com/onflow/go-ethereum/rlp"
+
+	"github.com/peterargue/flow-info/internal"
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/snapshots"
+)
+
+const (
+	defaultChainID      = "flow-localnet"
+	defaultStake        = 1000
+	defaultEpochViews   = 100_000
+	defaultDKGPhaseLen  = 1_000
+	defaultProtocolVer  = 0
+	defaultBlockSpacing = time.Second
+)
+
+// Node is a generated node, including its private keys.
+type Node struct {
+	identities.NodeInfo
+	StakingKey crypto.PrivateKey
+	NetworkKey crypto.PrivateKey
+	BeaconKey  crypto.PrivateKey
+}
+
+// Builder builds synthetic protocol state snapshots. All generated data is derived from the seed,
+// so a builder with the same configuration always produces the same snapshot.
+type Builder struct {
+	seed         int64
+	chainID      string
+	rootHeight   uint64
+	rootView     uint64
+	epochCounter uint64
+	blocks       int
+	clusters     int
+	nodeCounts   map[identities.Role]int
+	timestamp    time.Time
+	randomBeacon bool
+
+	random *rand.Rand
+	nodes  []Node
+}
+
+// NewBuilder returns a builder for a small network with a single spork root block.
+func NewBuilder() *Builder {
+	return &Builder{
+		chainID:  defaultChainID,
+		blocks:   1,
+		clusters: 1,
+		nodeCounts: map[identities.Role]int{
+			identities.RoleCollection:   2,
+			identities.RoleConsensus:    3,
+			identities.RoleExecution:    2,
+			identities.RoleVerification: 1,
+			identities.RoleAccess:       1,
+		},
+		timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// WithSeed sets the seed used to generate node IDs, keys and other random data.
+func (b *Builder) WithSeed(seed int64) *Builder {
+	b.seed = seed
+	return b
+}
+
+// WithChainID sets the chain ID of the snapshot.
+func (b *Builder) WithChainID(chainID string) *Builder {
+	b.chainID = chainID
+	return b
+}
+
+// WithRootHeight sets the height of the spork root block.
+func (b *Builder) WithRootHeight(height uint64) *Builder {
+	b.rootHeight = height
+	return b
+}
+
+// WithRootView sets the view of the spork root block.
+func (b *Builder) WithRootView(view uint64) *Builder {
+	b.rootView = view
+	return b
+}
+
+// WithEpochCounter sets the counter of the current epoch.
+func (b *Builder) WithEpochCounter(counter uint64) *Builder {
+	b.epochCounter = counter
+	return b
+}
+
+// WithNodes sets the number of nodes generated for the role.
+func (b *Builder) WithNodes(role identities.Role, count int) *Builder {
+	b.nodeCounts[role] = count
+	return b
+}
+
+// WithClusters sets the number of collection clusters.
+func (b *Builder) WithClusters(count int) *Builder {
+	b.clusters = count
+	return b
+}
+
+// WithBlocks sets the number of blocks in the sealing segment, including the spork root block.
+func (b *Builder) WithBlocks(count int) *Builder {
+	b.blocks = count
+	return b
+}
+
+// WithTimestamp sets the timestamp of the spork root block.
+func (b *Builder) WithTimestamp(timestamp time.Time) *Builder {
+	b.timestamp = timestamp.UTC()
+	return b
+}
+
+// WithRandomBeacon sets whether consensus nodes sign with their random beacon keys. When enabled,
+// the consensus nodes at even positions sign votes and proposals with their random beacon keys
+// and the others with their staking keys, so QCs include both signature types.
+func (b *Builder) WithRandomBeacon(enabled bool) *Builder {
+	b.randomBeacon = enabled
+	return b
+}
+
+// Nodes returns the nodes generated by the last call to Build.
+func (b *Builder) Nodes() []Node {
+	return b.nodes
+}
+
+// JSON builds the snapshot and returns it encoded as json.
+func (b *Builder) JSON() ([]byte, error) {
+	snapshot, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling snapshot json: %w", err)
+	}
+	return data, nil
+}
+
+// WriteFile builds the snapshot and writes it as json to the path.
+func (b *Builder) WriteFile(path string) error {
+	data, err := b.JSON()
+	if err != nil {
+		return err
+	}
+	return internal.WriteFile(path, data)
+}
+
+// Build builds the snapshot. The sealing segment starts with the spork root block, and each
+// following block is signed by a consensus node and includes a QC for its parent. The root QC
+// certifies the last block.
+func (b *Builder) Build() (*snapshots.Snapshot, error) {
+	if b.blocks < 1 {
+		return nil, fmt.Errorf("at least one block is required")
+	}
+	if b.nodeCounts[identities.RoleConsensus] < 1 {
+		return nil, fmt.Errorf("at least one consensus node is required")
+	}
+	if b.clusters < 1 || b.clusters > b.nodeCounts[identities.RoleCollection] {
+		return nil, fmt.Errorf("cluster count must be between 1 and the number of collection nodes")
+	}
+
+	b.random = rand.New(rand.NewSource(b.seed))
+
+	err := b.generateNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	setup := b.epochSetup()
+	commit, err := b.epochCommit(setup)
+	if err != nil {
+		return nil, err
+	}
+
+	protocolStateID := b.randomID()
+	segment, qc, err := b.sealingSegment(setup, protocolStateID)
+	if err != nil {
+		return nil, err
+	}
+
+	activeIdentities := make([]snapshots.DynamicIdentity, len(setup.Participants))
+	identityTable := make([]snapshots.Identity, len(setup.Participants))
+	for i, participant := range setup.Participants {
+		activeIdentities[i] = snapshots.DynamicIdentity{NodeID: participant.NodeID}
+		identityTable[i] = participant
+		identityTable[i].ParticipationStatus = snapshots.ParticipationStatusActive
+	}
+
+	segment.ProtocolStateEntries = map[string]snapshots.ProtocolStateEntry{
+		protocolStateID: {
+			EpochEntry: snapshots.EpochEntry{
+				CurrentEpoch: snapshots.Epoch{
+					SetupID:          b.randomID(),
+					CommitID:         b.randomID(),
+					ActiveIdentities: activeIdentities,
+				},
+				CurrentEpochSetup:         setup,
+				CurrentEpochCommit:        commit,
+				CurrentEpochIdentityTable: identityTable,
+			},
+		},
+	}
+
+	return &snapshots.Snapshot{
+		SealingSegment:    segment,
+		QuorumCertificate: qc,
+		Params: snapshots.Params{
+			ChainID:              b.chainID,
+			SporkID:              segment.Blocks[0].Header.ID,
+			SporkRootBlockHeight: b.rootHeight,
+			ProtocolVersion:      defaultProtocolVer,
+		},
+	}, nil
+}
+
+func (b *Builder) generateNodes() error {
+	b.nodes = nil
+	for _, role := range identities.Roles {
+		for i := 0; i < b.nodeCounts[role]; i++ {
+			stakingKey, err := crypto.GeneratePrivateKey(crypto.BLSBLS12381, b.randomBytes(crypto.KeyGenSeedMinLen))
+			if err != nil {
+				return fmt.Errorf("error generating staking key: %w", err)
+			}
+
+			networkKey, err := crypto.GeneratePrivateKey(crypto.ECDSASecp256k1, b.randomBytes(crypto.KeyGenSeedMinLen))
+			if err != nil {
+				return fmt.Errorf("error generating network key: %w", err)
+			}
+
+			node := Node{
+				NodeInfo: identities.NodeInfo{
+					Role:          role,
+					Address:       fmt.Sprintf("%s-%d.localnet:3569", role, i+1),
+					NodeID:        b.randomID(),
+					Stake:         defaultStake,
+					NetworkPubKey: hex.EncodeToString(networkKey.PublicKey().Encode()),
+					StakingPubKey: hex.EncodeToString(stakingKey.PublicKey().Encode()),
+				},
+				StakingKey: stakingKey,
+				NetworkKey: networkKey,
+			}
+
+			if role == identities.RoleConsensus {
+				node.BeaconKey, err = crypto.GeneratePrivateKey(crypto.BLSBLS12381, b.randomBytes(crypto.KeyGenSeedMinLen))
+				if err != nil {
+					return fmt.Errorf("error generating random beacon key: %w", err)
+				}
+			}
+
+			b.nodes = append(b.nodes, node)
+		}
+	}
+
+	// nodes are kept in canonical order, matching the order of the epoch participants
+	sort.Slice(b.nodes, func(i, j int) bool {
+		return b.nodes[i].NodeID < b.nodes[j].NodeID
+	})
+
+	return nil
+}
+
+func (b *Builder) epochSetup() snapshots.EpochSetup {
+	participants := make([]snapshots.Identity, len(b.nodes))
+	for i, node := range b.nodes {
+		info := node.NodeInfo
+		info.Stake = 0
+		participants[i] = snapshots.Identity{
+			NodeInfo:      info,
+			InitialWeight: node.Stake,
+		}
+	}
+
+	// assign collection nodes to clusters round-robin in canonical order
+	assignments := make([][]string, b.clusters)
+	collectors := 0
+	for _, node := range b.nodes {
+		if node.Role != identities.RoleCollection {
+			continue
+		}
+		cluster := collectors % b.clusters
+		assignments[cluster] = append(assignments[cluster], node.NodeID)
+		collectors++
+	}
+
+	firstView := b.rootView
+	return snapshots.EpochSetup{
+		Counter:            b.epochCounter,
+		FirstView:          firstView,
+		DKGPhase1FinalView: firstView + defaultDKGPhaseLen,
+		DKGPhase2FinalView: firstView + 2*defaultDKGPhaseLen,
+		DKGPhase3FinalView: firstView + 3*defaultDKGPhaseLen,
+		FinalView:          firstView + defaultEpochViews - 1,
+		Participants:       participants,
+		Assignments:        assignments,
+		RandomSource:       base64.StdEncoding.EncodeToString(b.randomBytes(16)),
+		TargetDuration:     uint64(defaultEpochViews),
+		TargetEndTime:      uint64(b.timestamp.Add(defaultEpochViews * time.Second).Unix()),
+	}
+}
+
+func (b *Builder) epochCommit(setup snapshots.EpochSetup) (snapshots.EpochCommit, error) {
+	commit := snapshots.EpochCommit{
+		Counter:     setup.Counter,
+		DKGIndexMap: make(map[string]int),
+	}
+
+	for range setup.Assignments {
+		commit.ClusterQCs = append(commit.ClusterQCs, snapshots.ClusterQC{})
+	}
+
+	// the group key is the aggregate of the participant keys. This is a valid key, but not the
+	// output of a real DKG.
+	var beaconKeys []crypto.PublicKey
+	for _, node := range b.nodes {
+		if node.BeaconKey == nil {
+			continue
+		}
+		commit.DKGIndexMap[node.NodeID] = len(commit.DKGParticipantKeys)
+		commit.DKGParticipantKeys = append(commit.DKGParticipantKeys, hex.EncodeToString(node.BeaconKey.PublicKey().Encode()))
+		beaconKeys = append(beaconKeys, node.BeaconKey.PublicKey())
+	}
+
+	groupKey, err := crypto.AggregateBLSPublicKeys(beaconKeys)
+	if err != nil {
+		return snapshots.EpochCommit{}, fmt.Errorf("error aggregating random beacon keys: %w", err)
+	}
+	commit.DKGGroupKey = hex.EncodeToString(groupKey.Encode())
+
+	return commit, nil
+}
+
+func (b *Builder) sealingSegment(setup snapshots.EpochSetup, protocolStateID string) (snapshots.SealingSegment, snapshots.QuorumCertificate, error) {
+	rootResult := snapshots.ExecutionResult{
+		PreviousResultID: b.randomID(),
+		Chunks:           []snapshots.Chunk{},
+		ServiceEvents:    []snapshots.ServiceEvent{},
+		ExecutionDataID:  b.randomID(),
+	}
+
+	var blocks []snapshots.Block
+	var parent snapshots.Header
+	for i := 0; i < b.blocks; i++ {
+		payload := snapshots.Payload{
+			Guarantees:      []snapshots.Guarantee{},
+			Seals:           []snapshots.Seal{},
+			Receipts:        []snapshots.Receipt{},
+			Results:         []snapshots.ExecutionResult{},
+			ProtocolStateID: protocolStateID,
+		}
+		payloadHash, err := payload.ComputeHash()
+		if err != nil {
+			return snapshots.SealingSegment{}, snapshots.QuorumCertificate{}, fmt.Errorf("error computing payload hash: %w", err)
+		}
+
+		header := snapshots.Header{
+			ChainID:            b.chainID,
+			Height:             b.rootHeight + uint64(i),
+			PayloadHash:        payloadHash,
+			Timestamp:          b.timestamp.Add(time.Duration(i) * defaultBlockSpacing).Format(time.RFC3339Nano),
+			View:               b.rootView + uint64(i),
+			ParentVoterIndices: "",
+			ParentVoterSigData: "",
+			ProposerID:         zeroID(),
+		}
+
+		if i == 0 {
+			header.ParentID = b.randomID()
+		} else {
+			header.ParentID = parent.ID
+			header.ParentView = parent.View
+
+			qc, err := b.quorumCertificate(setup, parent)
+			if err != nil {
+				return snapshots.SealingSegment{}, snapshots.QuorumCertificate{}, err
+			}
+			header.ParentVoterIndices = qc.SignerIndices
+			header.ParentVoterSigData = qc.SigData
+
+			consensus := b.consensusNodes()
+			header.ProposerID = consensus[i%len(consensus)].NodeID
+		}
+
+		id, err := header.ComputeID()
+		if err != nil {
+			return snapshots.SealingSegment{}, snapshots.QuorumCertificate{}, fmt.Errorf("error computing block ID: %w", err)
+		}
+		header.ID = id
+
+		if i > 0 {
+			consensus := b.consensusNodes()
+			sigType, sig, err := b.vote(consensus, i%len(consensus), header)
+			if err != nil {
+				return snapshots.SealingSegment{}, snapshots.QuorumCertificate{}, err
+			}
+			header.ProposerSigData = base64.StdEncoding.EncodeToString(append([]byte{sigType}, sig...))
+		}
+
+		blocks = append(blocks, snapshots.Block{
+			Header:  header,
+			Payload: payload,
+		})
+		parent = header
+	}
+
+	var err error
+	rootResult.BlockID = blocks[0].Header.ID
+	rootResult.ID, err = rootResult.ComputeID()
+	if err != nil {
+		return snapshots.SealingSegment{}, snapshots.QuorumCertificate{}, fmt.Errorf("error computing result ID: %w", err)
+	}
+
+	rootSeal := snapshots.Seal{
+		BlockID:                rootResult.BlockID,
+		ResultID:               rootResult.ID,
+		FinalState:             b.randomID(),
+		AggregatedApprovalSigs: []snapshots.AggregatedApprovalSig{},
+	}
+	rootSeal.ID, err = rootSeal.ComputeID()
+	if err != nil {
+		return snapshots.SealingSegment{}, snapshots.QuorumCertificate{}, fmt.Errorf("error computing seal ID: %w", err)
+	}
+
+	latestSeals := make(map[string]string, len(blocks))
+	for _, block := range blocks {
+		latestSeals[block.Header.ID] = rootSeal.ID
+	}
+
+	qc, err := b.quorumCertificate(setup, parent)
+	if err != nil {
+		return snapshots.SealingSegment{}, snapshots.QuorumCertificate{}, err
+	}
+
+	return snapshots.SealingSegment{
+		Blocks:           blocks,
+		ExtraBlocks:      []snapshots.Block{},
+		ExecutionResults: []snapshots.ExecutionResult{rootResult},
+		LatestSeals:      latestSeals,
+		FirstSeal:        snapshots.FirstSeal(rootSeal),
+	}, qc, nil
+}
+
+// quorumCertificate returns a QC for the block signed by all consensus nodes. If the random beacon
+// is enabled, the reconstructed random beacon signature is the aggregate of the signatures of all
+// DKG participants, which verifies against the builder's group key.
+func (b *Builder) quorumCertificate(setup snapshots.EpochSetup, header snapshots.Header) (snapshots.QuorumCertificate, error) {
+	consensus := b.consensusNodes()
+
+	signers := make(identities.IdentityList, len(consensus))
+	sigTypes := make([]byte, (len(consensus)+7)/8)
+	var stakingSigs, beaconSigs []crypto.Signature
+	for i, node := range consensus {
+		sigType, sig, err := b.vote(consensus, i, header)
+		if err != nil {
+			return snapshots.QuorumCertificate{}, err
+		}
+		signers[i] = node.NodeInfo
+
+		if sigType == snapshots.SigTypeRandomBeacon {
+			sigTypes[i/8] |= 1 << (7 - i%8)
+			beaconSigs = append(beaconSigs, sig)
+		} else {
+			stakingSigs = append(stakingSigs, sig)
+		}
+	}
+
+	sigData := snapshots.SignatureData{SigType: sigTypes}
+	var err error
+	if len(stakingSigs) > 0 {
+		sigData.AggregatedStakingSig, err = crypto.AggregateBLSSignatures(stakingSigs)
+		if err != nil {
+			return snapshots.QuorumCertificate{}, fmt.Errorf("error aggregating staking signatures: %w", err)
+		}
+	}
+	if len(beaconSigs) > 0 {
+		sigData.AggregatedRandomBeaconSig, err = crypto.AggregateBLSSignatures(beaconSigs)
+		if err != nil {
+			return snapshots.QuorumCertificate{}, fmt.Errorf("error aggregating random beacon signatures: %w", err)
+		}
+
+		var groupSigs []crypto.Signature
+		for _, node := range consensus {
+			sig, err := b.sign(node.BeaconKey, snapshots.RandomBeaconTag, header)
+			if err != nil {
+				return snapshots.QuorumCertificate{}, err
+			}
+			groupSigs = append(groupSigs, sig)
+		}
+		sigData.ReconstructedRandomBeaconSig, err = crypto.AggregateBLSSignatures(groupSigs)
+		if err != nil {
+			return snapshots.QuorumCertificate{}, fmt.Errorf("error aggregating random beacon signatures: %w", err)
+		}
+	}
+
+	signerIndices, err := snapshots.EncodeSignerIndices(setup.ConsensusCommittee(), signers)
+	if err != nil {
+		return snapshots.QuorumCertificate{}, fmt.Errorf("error encoding signer indices: %w", err)
+	}
+
+	encoded, err := rlp.EncodeToBytes(sigData)
+	if err != nil {
+		return snapshots.QuorumCertificate{}, fmt.Errorf("error encoding sig data: %w", err)
+	}
+
+	return snapshots.QuorumCertificate{
+		View:          header.View,
+		BlockID:       header.ID,
+		SignerIndices: base64.StdEncoding.EncodeToString(signerIndices),
+		SigData:       base64.StdEncoding.EncodeToString(encoded),
+	}, nil
+}
+
+// vote returns the signature type and the signature of the consensus node at the index for the
+// block.
+func (b *Builder) vote(consensus []Node, index int, header snapshots.Header) (byte, []byte, error) {
+	if b.randomBeacon && index%2 == 0 {
+		sig, err := b.sign(consensus[index].BeaconKey, snapshots.RandomBeaconTag, header)
+		return snapshots.SigTypeRandomBeacon, sig, err
+	}
+	sig, err := b.sign(consensus[index].StakingKey, snapshots.ConsensusVoteTag, header)
+	return snapshots.SigTypeStaking, sig, err
+}
+
+// sign signs the vote message for the block with the key and tag.
+func (b *Builder) sign(key crypto.PrivateKey, tag string, header snapshots.Header) ([]byte, error) {
+	msg, err := snapshots.VoteMessage(header.View, header.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := key.Sign(msg, crypto.NewExpandMsgXOFKMAC128(tag))
+	if err != nil {
+		return nil, fmt.Errorf("error signing block %s: %w", header.ID, err)
+	}
+	return sig, nil
+}
+
+func (b *Builder) consensusNodes() []Node {
+	var nodes []Node
+	for _, node := range b.nodes {
+		if node.Role == identities.RoleConsensus {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func (b *Builder) randomBytes(n int) []byte {
+	data := make([]byte, n)
+	_, _ = b.random.Read(data)
+	return data
+}
+
+func (b *Builder) randomID() string {
+	return hex.EncodeToString(b.randomBytes(32))
+}
+
+func zeroID() string {
+	return hex.EncodeToString(make([]byte, 32))
+}
@@ -0,0 +1,57 @@
+package snapshotstest
+
+import (
+	"testing"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/snapshots"
+)
+
+func TestBuildPassesValidation(t *testing.T) {
+	snapshot, err := NewBuilder().
+		WithSeed(42).
+		WithBlocks(5).
+		WithClusters(2).
+		WithNodes(identities.RoleCollection, 4).
+		Build()
+	if err != nil {
+		t.Fatalf("error building snapshot: %v", err)
+	}
+
+	for _, block := range snapshot.SealingSegment.Blocks {
+		hash, err := block.Payload.ComputeHash()
+		if err != nil {
+			t.Fatalf("error computing payload hash: %v", err)
+		}
+		if hash != block.Header.PayloadHash {
+			t.Errorf("block %d: payload hash %s, expected %s", block.Header.Height, block.Header.PayloadHash, hash)
+		}
+	}
+
+	if violations := snapshots.ValidateIDs(snapshot); len(violations) > 0 {
+		t.Errorf("unexpected ID violations: %v", violations)
+	}
+	if violations := snapshots.ValidateSealingSegment(snapshot); len(violations) > 0 {
+		t.Errorf("unexpected sealing segment violations: %v", violations)
+	}
+	if err := snapshots.VerifyQC(snapshot); err != nil {
+		t.Errorf("unexpected QC verification error: %v", err)
+	}
+	if err := snapshots.VerifySealingSegment(snapshot); err != nil {
+		t.Errorf("unexpected sealing segment verification error: %v", err)
+	}
+}
+
+func TestBuildIsDeterministic(t *testing.T) {
+	a, err := NewBuilder().WithSeed(1).WithBlocks(3).JSON()
+	if err != nil {
+		t.Fatalf("error building snapshot: %v", err)
+	}
+	b, err := NewBuilder().WithSeed(1).WithBlocks(3).JSON()
+	if err != nil {
+		t.Fatalf("error building snapshot: %v", err)
+	}
+	if string(a) != string(b) {
+		t.Error("snapshots built with the same seed differ")
+	}
+}
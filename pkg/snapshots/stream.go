@@ -0,0 +1,199 @@
+package snapshots
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/onflow/flow-go-sdk/access/grpc"
+
+	"github.com/peterargue/flow-info/internal"
+)
+
+// Part is a section of a snapshot that can be decoded on its own.
+type Part int
+
+const (
+	// PartParams is the snapshot's Params.
+	PartParams Part = 1 << iota
+	// PartQuorumCertificate is the snapshot's root QuorumCertificate.
+	PartQuorumCertificate
+	// PartProtocolState is the sealing segment's ProtocolStateEntries, which contain the epoch data
+	// and identity tables.
+	PartProtocolState
+	// PartSealingSegment is the rest of the sealing segment, including all blocks and results.
+	PartSealingSegment
+)
+
+// Decode decodes the selected parts of a snapshot from a stream. Parts that are not selected are
+// skipped without being decoded, so a large section like the sealing segment is never held in
+// memory as a whole.
+func Decode(r io.Reader, parts Part) (*Snapshot, error) {
+	decoder := json.NewDecoder(r)
+
+	var snapshot Snapshot
+	err := decodeObject(decoder, func(key string) error {
+		switch {
+		case key == "Params" && parts&PartParams != 0:
+			return decoder.Decode(&snapshot.Params)
+		case key == "QuorumCertificate" && parts&PartQuorumCertificate != 0:
+			return decoder.Decode(&snapshot.QuorumCertificate)
+		case key == "SealingSegment" && parts&(PartProtocolState|PartSealingSegment) != 0:
+			return decodeSealingSegment(decoder, &snapshot.SealingSegment, parts)
+		}
+		return skipValue(decoder, 1)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error decoding snapshot json: %w", err)
+	}
+
+	return &snapshot, nil
+}
+
+// LoadParts loads the selected parts of a snapshot from a local file or url.
+func LoadParts(url string, parts Part) (*Snapshot, error) {
+	var r io.ReadCloser
+	var err error
+
+	if strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
+		r, err = internal.OpenURL(url)
+		if err != nil {
+			return nil, fmt.Errorf("error downloading snapshot: %w", err)
+		}
+	} else {
+		r, err = os.Open(url)
+		if err != nil {
+			return nil, fmt.Errorf("error reading snapshot: %w", err)
+		}
+	}
+	defer r.Close()
+
+	return Decode(r, parts)
+}
+
+// LoadLatestPartsFromAN loads the selected parts of the latest snapshot from an access node.
+func LoadLatestPartsFromAN(ctx context.Context, accessClient *grpc.BaseClient, parts Part) (*Snapshot, error) {
+	data, err := accessClient.GetLatestProtocolStateSnapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading latest snapshot: %w", err)
+	}
+
+	return Decode(bytes.NewReader(data), parts)
+}
+
+func decodeSealingSegment(decoder *json.Decoder, segment *SealingSegment, parts Part) error {
+	return decodeObject(decoder, func(key string) error {
+		if key == "ProtocolStateEntries" {
+			if parts&PartProtocolState != 0 {
+				return decoder.Decode(&segment.ProtocolStateEntries)
+			}
+			return skipValue(decoder, 2)
+		}
+
+		if parts&PartSealingSegment == 0 {
+			return skipValue(decoder, 2)
+		}
+
+		switch key {
+		case "Blocks":
+			return decoder.Decode(&segment.Blocks)
+		case "ExtraBlocks":
+			return decoder.Decode(&segment.ExtraBlocks)
+		case "ExecutionResults":
+			return decoder.Decode(&segment.ExecutionResults)
+		case "LatestSeals":
+			return decoder.Decode(&segment.LatestSeals)
+		case "FirstSeal":
+			return decoder.Decode(&segment.FirstSeal)
+		}
+		return skipValue(decoder, 2)
+	})
+}
+
+// decodeObject reads a json object from the decoder, calling decodeField for each key. decodeField
+// must consume the key's value.
+func decodeObject(decoder *json.Decoder, decodeField func(key string) error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if token == nil {
+		return nil
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected object, got %v", token)
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("expected object key, got %v", token)
+		}
+
+		err = decodeField(key)
+		if err != nil {
+			return fmt.Errorf("error decoding %s: %w", key, err)
+		}
+	}
+
+	// consume the closing delimiter
+	_, err = decoder.Token()
+	return err
+}
+
+// skipWholeLevel is the nesting level in the snapshot from which skipValue skips values whole,
+// which is faster than reading them token by token but buffers them. The fields of the snapshot
+// are at level 1, so level 3 holds the elements of the sealing segment's arrays, like single
+// blocks and results.
+const skipWholeLevel = 3
+
+// skipValue reads the next value from the decoder without decoding it. level is the nesting level
+// of the value in the snapshot.
+func skipValue(decoder *json.Decoder, level int) error {
+	if level >= skipWholeLevel {
+		return decoder.Decode(&discard{})
+	}
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	for decoder.More() {
+		if delim == '{' {
+			if _, err := decoder.Token(); err != nil {
+				return err
+			}
+		}
+		if err := skipValue(decoder, level+1); err != nil {
+			return err
+		}
+	}
+
+	// consume the closing delimiter
+	_, err = decoder.Token()
+	return err
+}
+
+// discard is a json value that is validated but not decoded.
+type discard struct{}
+
+func (*discard) UnmarshalJSON([]byte) error {
+	return nil
+}
@@ -0,0 +1,153 @@
+package snapshots_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/snapshots"
+	"github.com/peterargue/flow-info/pkg/snapshots/snapshotstest"
+)
+
+var (
+	largeSnapshotOnce sync.Once
+	largeSnapshotData []byte
+	largeSnapshotErr  error
+)
+
+// largeSnapshot returns the json of a snapshot with a mainnet sized identity table and a sealing
+// segment padded with execution results, so it is dominated by the parts Decode can skip.
+func largeSnapshot(tb testing.TB) []byte {
+	tb.Helper()
+
+	largeSnapshotOnce.Do(func() {
+		snapshot, err := snapshotstest.NewBuilder().
+			WithSeed(1).
+			WithBlocks(50).
+			WithClusters(4).
+			WithNodes(identities.RoleCollection, 100).
+			WithNodes(identities.RoleConsensus, 5).
+			WithNodes(identities.RoleExecution, 10).
+			WithNodes(identities.RoleVerification, 50).
+			WithNodes(identities.RoleAccess, 100).
+			Build()
+		if err != nil {
+			largeSnapshotErr = err
+			return
+		}
+
+		segment := &snapshot.SealingSegment
+		result := segment.ExecutionResults[0]
+		for i := 0; i < 2000; i++ {
+			padded := result
+			padded.Chunks = make([]snapshots.Chunk, 10)
+			for j := range padded.Chunks {
+				padded.Chunks[j] = snapshots.Chunk{
+					CollectionIndex: uint64(j),
+					StartState:      result.ID,
+					EventCollection: result.ID,
+					BlockID:         result.BlockID,
+					Index:           uint64(j),
+					EndState:        result.ID,
+				}
+			}
+			segment.ExecutionResults = append(segment.ExecutionResults, padded)
+		}
+
+		largeSnapshotData, largeSnapshotErr = json.Marshal(snapshot)
+	})
+
+	if largeSnapshotErr != nil {
+		tb.Fatalf("error building snapshot: %v", largeSnapshotErr)
+	}
+	return largeSnapshotData
+}
+
+func TestDecodeParts(t *testing.T) {
+	data := largeSnapshot(t)
+
+	var full snapshots.Snapshot
+	if err := json.Unmarshal(data, &full); err != nil {
+		t.Fatal(err)
+	}
+
+	partial, err := snapshots.Decode(bytes.NewReader(data), snapshots.PartParams|snapshots.PartQuorumCertificate|snapshots.PartProtocolState)
+	if err != nil {
+		t.Fatalf("error decoding snapshot: %v", err)
+	}
+
+	if !reflect.DeepEqual(partial.Params, full.Params) {
+		t.Error("params differ from the full decode")
+	}
+	if !reflect.DeepEqual(partial.QuorumCertificate, full.QuorumCertificate) {
+		t.Error("quorum certificate differs from the full decode")
+	}
+	if !reflect.DeepEqual(partial.SealingSegment.ProtocolStateEntries, full.SealingSegment.ProtocolStateEntries) {
+		t.Error("protocol state entries differ from the full decode")
+	}
+	if len(partial.SealingSegment.Blocks) != 0 || len(partial.SealingSegment.ExecutionResults) != 0 {
+		t.Error("sealing segment was decoded but not selected")
+	}
+
+	all, err := snapshots.Decode(bytes.NewReader(data), snapshots.PartParams|snapshots.PartQuorumCertificate|snapshots.PartProtocolState|snapshots.PartSealingSegment)
+	if err != nil {
+		t.Fatalf("error decoding snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(all.SealingSegment, full.SealingSegment) {
+		t.Error("sealing segment differs from the full decode")
+	}
+}
+
+// The benchmarks compare decoding the whole snapshot the way Load does, by reading it into memory
+// and unmarshalling it, with streaming selected parts using Decode. The reported bytes and
+// allocations are totals per decode, not peak memory. Decode skips unselected parts without
+// unmarshalling them, and holds at most one block or result of a skipped array in memory, while
+// Load holds the whole document and the decoded snapshot.
+
+func BenchmarkDecodeFull(b *testing.B) {
+	data := largeSnapshot(b)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		read, err := io.ReadAll(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		var snapshot snapshots.Snapshot
+		if err := json.Unmarshal(read, &snapshot); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeParams(b *testing.B) {
+	benchmarkDecode(b, snapshots.PartParams)
+}
+
+func BenchmarkDecodeProtocolState(b *testing.B) {
+	benchmarkDecode(b, snapshots.PartParams|snapshots.PartQuorumCertificate|snapshots.PartProtocolState)
+}
+
+func BenchmarkDecodeAllParts(b *testing.B) {
+	benchmarkDecode(b, snapshots.PartParams|snapshots.PartQuorumCertificate|snapshots.PartProtocolState|snapshots.PartSealingSegment)
+}
+
+func benchmarkDecode(b *testing.B, parts snapshots.Part) {
+	data := largeSnapshot(b)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := snapshots.Decode(bytes.NewReader(data), parts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
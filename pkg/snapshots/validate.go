@@ -0,0 +1,122 @@
+package snapshots
+
+import (
+	"fmt"
+)
+
+// ValidateSealingSegment checks the sealing segment of the snapshot for structural consistency.
+// It returns all violations found, or nil if the sealing segment is consistent.
+func ValidateSealingSegment(snapshot *Snapshot) []error {
+	var violations []error
+	addViolation := func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Errorf(format, args...))
+	}
+
+	segment := snapshot.SealingSegment
+	if len(segment.Blocks) == 0 {
+		addViolation("sealing segment has no blocks")
+		return violations
+	}
+
+	// blocks must form a chain, with the extra blocks extending below the segment's lowest block
+	chain := make([]Block, 0, len(segment.ExtraBlocks)+len(segment.Blocks))
+	chain = append(chain, segment.ExtraBlocks...)
+	chain = append(chain, segment.Blocks...)
+	for i := 1; i < len(chain); i++ {
+		parent := chain[i-1].Header
+		header := chain[i].Header
+
+		if header.ParentID != parent.ID {
+			addViolation("block %s: parent ID %s does not match previous block %s", header.ID, header.ParentID, parent.ID)
+		}
+		if header.Height != parent.Height+1 {
+			addViolation("block %s: height %d does not follow previous block height %d", header.ID, header.Height, parent.Height)
+		}
+		if header.ParentView != parent.View {
+			addViolation("block %s: parent view %d does not match previous block view %d", header.ID, header.ParentView, parent.View)
+		}
+	}
+
+	for _, block := range chain {
+		header := block.Header
+		if header.Height > snapshot.Params.SporkRootBlockHeight && header.View <= header.ParentView {
+			addViolation("block %s: view %d is not greater than parent view %d", header.ID, header.View, header.ParentView)
+		}
+		if header.ChainID != snapshot.Params.ChainID {
+			addViolation("block %s: chain ID %s does not match params chain ID %s", header.ID, header.ChainID, snapshot.Params.ChainID)
+		}
+		if header.Height < snapshot.Params.SporkRootBlockHeight {
+			addViolation("block %s: height %d is below spork root block height %d", header.ID, header.Height, snapshot.Params.SporkRootBlockHeight)
+		}
+	}
+
+	// index the seals and results included in the segment
+	var sealList []Seal
+	seals := make(map[string]Seal)
+	results := make(map[string]ExecutionResult)
+	for _, block := range chain {
+		sealList = append(sealList, block.Payload.Seals...)
+		for _, seal := range block.Payload.Seals {
+			seals[seal.ID] = seal
+		}
+		for _, result := range block.Payload.Results {
+			results[result.ID] = result
+		}
+	}
+	for _, result := range segment.ExecutionResults {
+		results[result.ID] = result
+	}
+	if segment.FirstSeal.ID != "" {
+		sealList = append(sealList, Seal(segment.FirstSeal))
+		seals[segment.FirstSeal.ID] = Seal(segment.FirstSeal)
+	}
+
+	// every block must have a latest seal, which must be included in the segment
+	blockIDs := make(map[string]bool, len(segment.Blocks))
+	for _, block := range segment.Blocks {
+		blockIDs[block.Header.ID] = true
+
+		sealID, ok := segment.LatestSeals[block.Header.ID]
+		if !ok {
+			addViolation("block %s: missing latest seal", block.Header.ID)
+			continue
+		}
+		if _, ok := seals[sealID]; !ok {
+			addViolation("block %s: latest seal %s is not included in the sealing segment", block.Header.ID, sealID)
+		}
+	}
+	for blockID := range segment.LatestSeals {
+		if !blockIDs[blockID] {
+			addViolation("latest seal references block %s which is not in the sealing segment", blockID)
+		}
+	}
+
+	// the first seal is the latest seal as of the lowest block
+	lowest := segment.Blocks[0].Header
+	if segment.FirstSeal.ID != "" && segment.LatestSeals[lowest.ID] != segment.FirstSeal.ID {
+		addViolation("first seal %s is not the latest seal for the lowest block %s", segment.FirstSeal.ID, lowest.ID)
+	}
+
+	// every seal must have its execution result in the segment
+	for _, seal := range sealList {
+		result, ok := results[seal.ResultID]
+		if !ok {
+			addViolation("seal %s: execution result %s is not included in the sealing segment", seal.ID, seal.ResultID)
+			continue
+		}
+		if result.BlockID != seal.BlockID {
+			addViolation("seal %s: sealed block %s does not match execution result block %s", seal.ID, seal.BlockID, result.BlockID)
+		}
+	}
+
+	// the root QC must certify the head of the segment
+	head := segment.Blocks[len(segment.Blocks)-1].Header
+	if snapshot.QuorumCertificate.BlockID != head.ID {
+		addViolation("root QC block ID %s does not match head block %s", snapshot.QuorumCertificate.BlockID, head.ID)
+	}
+	if snapshot.QuorumCertificate.View != head.View {
+		addViolation("root QC view %d does not match head block view %d", snapshot.QuorumCertificate.View, head.View)
+	}
+
+	return violations
+}
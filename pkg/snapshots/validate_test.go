@@ -0,0 +1,165 @@
+package snapshots_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/peterargue/flow-info/pkg/snapshots"
+)
+
+func TestValidateSealingSegment(t *testing.T) {
+	other := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name   string
+		tamper func(s *snapshots.Snapshot)
+		want   []string
+	}{
+		{
+			name:   "valid",
+			tamper: func(s *snapshots.Snapshot) {},
+		},
+		{
+			name: "no blocks",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.Blocks = nil
+			},
+			want: []string{"sealing segment has no blocks"},
+		},
+		{
+			name: "height gap",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.Blocks[2].Header.Height++
+			},
+			want: []string{"height 3 does not follow previous block height 1"},
+		},
+		{
+			name: "parent mismatch",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.Blocks[2].Header.ParentID = other
+			},
+			want: []string{"parent ID " + other + " does not match previous block"},
+		},
+		{
+			name: "parent view mismatch",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.Blocks[2].Header.ParentView = 0
+			},
+			want: []string{"parent view 0 does not match previous block view 1"},
+		},
+		{
+			name: "view not increasing",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.Blocks[2].Header.View = 1
+				s.QuorumCertificate.View = 1
+			},
+			want: []string{"view 1 is not greater than parent view 1"},
+		},
+		{
+			name: "chain ID",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.Blocks[1].Header.ChainID = "flow-testnet"
+			},
+			want: []string{"chain ID flow-testnet does not match params chain ID flow-localnet"},
+		},
+		{
+			name: "below spork root",
+			tamper: func(s *snapshots.Snapshot) {
+				s.Params.SporkRootBlockHeight = 1
+			},
+			want: []string{"height 0 is below spork root block height 1"},
+		},
+		{
+			name: "missing seal",
+			tamper: func(s *snapshots.Snapshot) {
+				delete(s.SealingSegment.LatestSeals, s.SealingSegment.Blocks[1].Header.ID)
+			},
+			want: []string{"missing latest seal"},
+		},
+		{
+			name: "seal not in segment",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.LatestSeals[s.SealingSegment.Blocks[2].Header.ID] = other
+			},
+			want: []string{"latest seal " + other + " is not included in the sealing segment"},
+		},
+		{
+			name: "seal for unknown block",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.LatestSeals[other] = s.SealingSegment.FirstSeal.ID
+			},
+			want: []string{"latest seal references block " + other},
+		},
+		{
+			name: "first seal not latest",
+			tamper: func(s *snapshots.Snapshot) {
+				// a second copy of the seal with another ID, recorded for the lowest block
+				seal := snapshots.Seal(s.SealingSegment.FirstSeal)
+				seal.ID = other
+				s.SealingSegment.Blocks[2].Payload.Seals = append(s.SealingSegment.Blocks[2].Payload.Seals, seal)
+				s.SealingSegment.LatestSeals[s.SealingSegment.Blocks[0].Header.ID] = other
+			},
+			want: []string{"is not the latest seal for the lowest block"},
+		},
+		{
+			name: "missing result",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.FirstSeal.ResultID = other
+			},
+			want: []string{"execution result " + other + " is not included in the sealing segment"},
+		},
+		{
+			name: "result for another block",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.ExecutionResults[0].BlockID = other
+			},
+			want: []string{"does not match execution result block " + other},
+		},
+		{
+			name: "QC for another block",
+			tamper: func(s *snapshots.Snapshot) {
+				s.QuorumCertificate.BlockID = other
+			},
+			want: []string{"root QC block ID " + other + " does not match head block"},
+		},
+		{
+			name: "QC view",
+			tamper: func(s *snapshots.Snapshot) {
+				s.QuorumCertificate.View = 7
+			},
+			want: []string{"root QC view 7 does not match head block view 2"},
+		},
+		{
+			name: "all violations are collected",
+			tamper: func(s *snapshots.Snapshot) {
+				s.SealingSegment.Blocks[2].Header.Height++
+				delete(s.SealingSegment.LatestSeals, s.SealingSegment.Blocks[1].Header.ID)
+				s.SealingSegment.ExecutionResults = nil
+				s.QuorumCertificate.View = 7
+			},
+			want: []string{
+				"does not follow previous block height",
+				"missing latest seal",
+				"is not included in the sealing segment",
+				"root QC view 7",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			snapshot := buildSnapshot(t, 3)
+			test.tamper(snapshot)
+
+			violations := snapshots.ValidateSealingSegment(snapshot)
+			if len(violations) != len(test.want) {
+				t.Fatalf("expected %d violations, got %d: %v", len(test.want), len(violations), violations)
+			}
+			for i, want := range test.want {
+				if !strings.Contains(violations[i].Error(), want) {
+					t.Errorf("violation %q does not contain %q", violations[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package snapshots
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/onflow/crypto"
+	"github.com/onflow/go-ethereum/rlp"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+)
+
+// Signing tags used by consensus nodes, matching flow-go's module/signature package.
+const (
+	ConsensusVoteTag = "FLOW-Consensus_Vote-V00-CS00-with-"
+	RandomBeaconTag  = "FLOW-Random_Beacon-V00-CS00-with-"
+)
+
+// SignatureData is the RLP encoded contents of a QC's SigData.
+type SignatureData struct {
+	SigType                      []byte
+	AggregatedStakingSig         []byte
+	AggregatedRandomBeaconSig    []byte
+	ReconstructedRandomBeaconSig []byte
+}
+
+// VerifyQC verifies the BLS signatures of the snapshot's root QC against the keys of the current
+// epoch's consensus committee.
+func VerifyQC(snapshot *Snapshot) error {
+	entry := snapshot.SealingSegment.ProtocolStateEntry().EpochEntry
+	return snapshot.QuorumCertificate.Verify(entry.CurrentEpochSetup, entry.CurrentEpochCommit)
+}
+
+// VerifySealingSegment verifies the proposer signature and the parent QC of each block in the
+// snapshot's sealing segment.
+func VerifySealingSegment(snapshot *Snapshot) error {
+	entry := snapshot.SealingSegment.ProtocolStateEntry().EpochEntry
+
+	for _, block := range snapshot.SealingSegment.Blocks {
+		header := block.Header
+
+		// the spork root block is not signed, and has no parent QC
+		if header.Height == snapshot.Params.SporkRootBlockHeight {
+			continue
+		}
+
+		setup, commit := entry.epochForView(header.View)
+		err := header.VerifyProposerSig(setup, commit)
+		if err != nil {
+			return fmt.Errorf("error verifying proposer signature for block %s: %w", header.ID, err)
+		}
+
+		setup, commit = entry.epochForView(header.ParentView)
+		err = header.ParentQC().Verify(setup, commit)
+		if err != nil {
+			return fmt.Errorf("error verifying parent QC for block %s: %w", header.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// ParentQC returns the QC for the block's parent that is embedded in the header.
+func (h Header) ParentQC() QuorumCertificate {
+	return QuorumCertificate{
+		View:          h.ParentView,
+		BlockID:       h.ParentID,
+		SignerIndices: h.ParentVoterIndices,
+		SigData:       h.ParentVoterSigData,
+	}
+}
+
+// Signature types of a single consensus signature, matching flow-go's model/encoding package.
+// The type is encoded as a one byte prefix of the signature.
+const (
+	SigTypeStaking      byte = 0
+	SigTypeRandomBeacon byte = 1
+)
+
+// VerifyProposerSig verifies the proposer's signature on the block. The signature is either a
+// staking or a random beacon signature, depending on its type prefix.
+func (h Header) VerifyProposerSig(setup EpochSetup, commit EpochCommit) error {
+	proposer := setup.ConsensusCommittee().ByNodeID(h.ProposerID)
+	if proposer == nil {
+		return fmt.Errorf("proposer %s is not a member of the consensus committee", h.ProposerID)
+	}
+
+	data, err := decodeBytes(h.ProposerSigData)
+	if err != nil {
+		return fmt.Errorf("error decoding proposer signature: %w", err)
+	}
+
+	sigType, sig, err := decodeSingleSig(data)
+	if err != nil {
+		return fmt.Errorf("error decoding proposer signature: %w", err)
+	}
+
+	var key crypto.PublicKey
+	var tag string
+	switch sigType {
+	case SigTypeStaking:
+		key, err = stakingKey(*proposer)
+		tag = ConsensusVoteTag
+	case SigTypeRandomBeacon:
+		key, err = commit.beaconKey(setup, proposer.NodeID)
+		tag = RandomBeaconTag
+	}
+	if err != nil {
+		return err
+	}
+
+	msg, err := VoteMessage(h.View, h.ID)
+	if err != nil {
+		return err
+	}
+
+	valid, err := key.Verify(sig, msg, crypto.NewExpandMsgXOFKMAC128(tag))
+	if err != nil {
+		return fmt.Errorf("error verifying proposer signature: %w", err)
+	}
+	if !valid {
+		return fmt.Errorf("invalid proposer signature")
+	}
+
+	return nil
+}
+
+// Verify verifies the QC's aggregated staking and random beacon signatures against the keys in
+// the epoch's setup and commit events.
+func (qc QuorumCertificate) Verify(setup EpochSetup, commit EpochCommit) error {
+	signers, err := qc.Signers(setup)
+	if err != nil {
+		return fmt.Errorf("error decoding signers: %w", err)
+	}
+
+	data, err := decodeBytes(qc.SigData)
+	if err != nil {
+		return fmt.Errorf("error decoding sig data: %w", err)
+	}
+
+	var sigData SignatureData
+	err = rlp.DecodeBytes(data, &sigData)
+	if err != nil {
+		return fmt.Errorf("error decoding sig data: %w", err)
+	}
+
+	stakingSigners, beaconSigners, err := decodeSigTypes(signers, sigData.SigType)
+	if err != nil {
+		return err
+	}
+
+	msg, err := VoteMessage(qc.View, qc.BlockID)
+	if err != nil {
+		return err
+	}
+
+	if len(stakingSigners) > 0 {
+		keys := make([]crypto.PublicKey, len(stakingSigners))
+		for i, signer := range stakingSigners {
+			keys[i], err = stakingKey(signer)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = verifyAggregate(keys, sigData.AggregatedStakingSig, msg, ConsensusVoteTag)
+		if err != nil {
+			return fmt.Errorf("error verifying aggregated staking signature: %w", err)
+		}
+	}
+
+	if len(beaconSigners) > 0 {
+		keys := make([]crypto.PublicKey, len(beaconSigners))
+		for i, signer := range beaconSigners {
+			keys[i], err = commit.beaconKey(setup, signer.NodeID)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = verifyAggregate(keys, sigData.AggregatedRandomBeaconSig, msg, RandomBeaconTag)
+		if err != nil {
+			return fmt.Errorf("error verifying aggregated random beacon signature: %w", err)
+		}
+
+		groupKey, err := decodeBLSKey(commit.DKGGroupKey)
+		if err != nil {
+			return fmt.Errorf("invalid DKG group key: %w", err)
+		}
+
+		err = verifyAggregate([]crypto.PublicKey{groupKey}, sigData.ReconstructedRandomBeaconSig, msg, RandomBeaconTag)
+		if err != nil {
+			return fmt.Errorf("error verifying reconstructed random beacon signature: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// beaconKey returns the random beacon public key for the node. If the commit does not include a
+// DKG index map, the node's index in the consensus committee is used.
+func (c EpochCommit) beaconKey(setup EpochSetup, nodeID string) (crypto.PublicKey, error) {
+	index, ok := c.DKGIndexMap[nodeID]
+	if !ok {
+		index = -1
+		for i, member := range setup.ConsensusCommittee() {
+			if member.NodeID == nodeID {
+				index = i
+				break
+			}
+		}
+	}
+
+	if index < 0 || index >= len(c.DKGParticipantKeys) {
+		return nil, fmt.Errorf("no random beacon key for node %s", nodeID)
+	}
+
+	key, err := decodeBLSKey(c.DKGParticipantKeys[index])
+	if err != nil {
+		return nil, fmt.Errorf("invalid random beacon key for node %s: %w", nodeID, err)
+	}
+	return key, nil
+}
+
+// epochForView returns the setup and commit events for the epoch containing the view.
+func (e *EpochEntry) epochForView(view uint64) (EpochSetup, EpochCommit) {
+	if view < e.CurrentEpochSetup.FirstView && e.PreviousEpochSetup.Counter > 0 {
+		return e.PreviousEpochSetup, e.PreviousEpochCommit
+	}
+	return e.CurrentEpochSetup, e.CurrentEpochCommit
+}
+
+// decodeSigTypes splits the signers into staking and random beacon signers. The sig types are
+// encoded as a bit vector with one bit per signer, where a set bit marks a random beacon signature.
+func decodeSigTypes(signers identities.IdentityList, sigTypes []byte) (identities.IdentityList, identities.IdentityList, error) {
+	if len(sigTypes) != bitVectorLen(len(signers)) {
+		return nil, nil, fmt.Errorf("invalid sig types length: expected %d bytes for %d signers, got %d",
+			bitVectorLen(len(signers)), len(signers), len(sigTypes))
+	}
+
+	for i := len(signers); i < len(sigTypes)*8; i++ {
+		if readBit(sigTypes, i) {
+			return nil, nil, fmt.Errorf("invalid sig types: padding bit %d is set", i)
+		}
+	}
+
+	var staking, beacon identities.IdentityList
+	for i, signer := range signers {
+		if readBit(sigTypes, i) {
+			beacon = append(beacon, signer)
+		} else {
+			staking = append(staking, signer)
+		}
+	}
+	return staking, beacon, nil
+}
+
+// decodeSingleSig splits a single consensus signature into its type and the signature itself.
+func decodeSingleSig(data []byte) (byte, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, fmt.Errorf("empty signature")
+	}
+
+	sigType := data[0]
+	if sigType != SigTypeStaking && sigType != SigTypeRandomBeacon {
+		return 0, nil, fmt.Errorf("unknown signature type %d", sigType)
+	}
+	return sigType, data[1:], nil
+}
+
+// VoteMessage returns the message signed by consensus nodes when voting for a block.
+func VoteMessage(view uint64, blockID string) ([]byte, error) {
+	id, err := decodeIdentifier(blockID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block ID: %w", err)
+	}
+
+	msg, err := makeID(struct {
+		BlockID identifier
+		View    uint64
+	}{
+		BlockID: id,
+		View:    view,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating vote message: %w", err)
+	}
+
+	return msg[:], nil
+}
+
+func verifyAggregate(keys []crypto.PublicKey, sig []byte, msg []byte, tag string) error {
+	valid, err := crypto.VerifyBLSSignatureOneMessage(keys, sig, msg, crypto.NewExpandMsgXOFKMAC128(tag))
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+func stakingKey(node identities.NodeInfo) (crypto.PublicKey, error) {
+	key, err := node.StakingKey()
+	if err != nil {
+		return nil, fmt.Errorf("invalid staking key for node %s: %w", node.NodeID, err)
+	}
+	return key, nil
+}
+
+func decodeBLSKey(key string) (crypto.PublicKey, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	return crypto.DecodePublicKey(crypto.BLSBLS12381, data)
+}
@@ -0,0 +1,377 @@
+package snapshots_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/onflow/crypto"
+	"github.com/onflow/go-ethereum/rlp"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/snapshots"
+	"github.com/peterargue/flow-info/pkg/snapshots/snapshotstest"
+)
+
+// buildSignedSnapshot builds a snapshot with four blocks, and returns it with the generated nodes.
+// With the random beacon enabled, block 2 is proposed with a random beacon signature and block 1
+// and 3 with staking signatures.
+func buildSignedSnapshot(t *testing.T, randomBeacon bool) (*snapshots.Snapshot, []snapshotstest.Node) {
+	t.Helper()
+
+	builder := snapshotstest.NewBuilder().WithSeed(13).WithBlocks(4).WithRandomBeacon(randomBeacon)
+	snapshot, err := builder.Build()
+	if err != nil {
+		t.Fatalf("error building snapshot: %v", err)
+	}
+	return snapshot, builder.Nodes()
+}
+
+func sigData(t *testing.T, data string) snapshots.SignatureData {
+	t.Helper()
+
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sigData snapshots.SignatureData
+	if err := rlp.DecodeBytes(raw, &sigData); err != nil {
+		t.Fatal(err)
+	}
+	return sigData
+}
+
+func encodeSigData(t *testing.T, sigData snapshots.SignatureData) string {
+	t.Helper()
+
+	raw, err := rlp.EncodeToBytes(sigData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// flipBit returns a copy of the base64 encoded data with a bit of the last byte flipped.
+func flipBit(t *testing.T, data string) string {
+	t.Helper()
+
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)-1] ^= 0x01
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func proposerSig(t *testing.T, header snapshots.Header) (byte, []byte) {
+	t.Helper()
+
+	raw, err := base64.StdEncoding.DecodeString(header.ProposerSigData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw[0], raw[1:]
+}
+
+func TestVerifySnapshot(t *testing.T) {
+	for _, randomBeacon := range []bool{false, true} {
+		snapshot, _ := buildSignedSnapshot(t, randomBeacon)
+
+		if err := snapshots.VerifyQC(snapshot); err != nil {
+			t.Errorf("random beacon %t: unexpected QC error: %v", randomBeacon, err)
+		}
+		if err := snapshots.VerifySealingSegment(snapshot); err != nil {
+			t.Errorf("random beacon %t: unexpected sealing segment error: %v", randomBeacon, err)
+		}
+
+		// the builder proposes block 2 with a random beacon signature when the beacon is enabled
+		want := snapshots.SigTypeStaking
+		if randomBeacon {
+			want = snapshots.SigTypeRandomBeacon
+		}
+		if sigType, _ := proposerSig(t, snapshot.SealingSegment.Blocks[2].Header); sigType != want {
+			t.Errorf("random beacon %t: expected sig type %d, got %d", randomBeacon, want, sigType)
+		}
+	}
+}
+
+func TestVerifyProposerSig(t *testing.T) {
+	tests := []struct {
+		name   string
+		block  int
+		tamper func(h *snapshots.Header, commit *snapshots.EpochCommit, nodes []snapshotstest.Node)
+		want   string
+	}{
+		{
+			name:  "staking signature",
+			block: 1,
+		},
+		{
+			name:  "random beacon signature",
+			block: 2,
+		},
+		{
+			name:  "wrong key",
+			block: 1,
+			tamper: func(h *snapshots.Header, commit *snapshots.EpochCommit, nodes []snapshotstest.Node) {
+				// a valid signature by another consensus node
+				for _, node := range nodes {
+					if node.Role == identities.RoleConsensus && node.NodeID != h.ProposerID {
+						h.ProposerSigData = signVote(t, node.StakingKey, snapshots.SigTypeStaking, *h)
+						return
+					}
+				}
+			},
+			want: "invalid proposer signature",
+		},
+		{
+			name:  "wrong beacon key",
+			block: 2,
+			tamper: func(h *snapshots.Header, commit *snapshots.EpochCommit, nodes []snapshotstest.Node) {
+				for _, node := range nodes {
+					if node.Role == identities.RoleConsensus && node.NodeID != h.ProposerID {
+						h.ProposerSigData = signVote(t, node.BeaconKey, snapshots.SigTypeRandomBeacon, *h)
+						return
+					}
+				}
+			},
+			want: "invalid proposer signature",
+		},
+		{
+			name:  "flipped bit",
+			block: 1,
+			tamper: func(h *snapshots.Header, commit *snapshots.EpochCommit, nodes []snapshotstest.Node) {
+				h.ProposerSigData = flipBit(t, h.ProposerSigData)
+			},
+			want: "proposer signature",
+		},
+		{
+			name:  "flipped bit in beacon signature",
+			block: 2,
+			tamper: func(h *snapshots.Header, commit *snapshots.EpochCommit, nodes []snapshotstest.Node) {
+				h.ProposerSigData = flipBit(t, h.ProposerSigData)
+			},
+			want: "proposer signature",
+		},
+		{
+			name:  "staking signature with beacon type",
+			block: 1,
+			tamper: func(h *snapshots.Header, commit *snapshots.EpochCommit, nodes []snapshotstest.Node) {
+				_, sig := proposerSig(t, *h)
+				h.ProposerSigData = base64.StdEncoding.EncodeToString(append([]byte{snapshots.SigTypeRandomBeacon}, sig...))
+			},
+			want: "invalid proposer signature",
+		},
+		{
+			name:  "beacon signature with staking type",
+			block: 2,
+			tamper: func(h *snapshots.Header, commit *snapshots.EpochCommit, nodes []snapshotstest.Node) {
+				_, sig := proposerSig(t, *h)
+				h.ProposerSigData = base64.StdEncoding.EncodeToString(append([]byte{snapshots.SigTypeStaking}, sig...))
+			},
+			want: "invalid proposer signature",
+		},
+		{
+			name:  "unknown signature type",
+			block: 1,
+			tamper: func(h *snapshots.Header, commit *snapshots.EpochCommit, nodes []snapshotstest.Node) {
+				_, sig := proposerSig(t, *h)
+				h.ProposerSigData = base64.StdEncoding.EncodeToString(append([]byte{2}, sig...))
+			},
+			want: "unknown signature type 2",
+		},
+		{
+			name:  "empty signature",
+			block: 1,
+			tamper: func(h *snapshots.Header, commit *snapshots.EpochCommit, nodes []snapshotstest.Node) {
+				h.ProposerSigData = ""
+			},
+			want: "empty signature",
+		},
+		{
+			name:  "non-committee proposer",
+			block: 1,
+			tamper: func(h *snapshots.Header, commit *snapshots.EpochCommit, nodes []snapshotstest.Node) {
+				// an execution node signing with its own staking key is still rejected
+				for _, node := range nodes {
+					if node.Role == identities.RoleExecution {
+						h.ProposerID = node.NodeID
+						h.ProposerSigData = signVote(t, node.StakingKey, snapshots.SigTypeStaking, *h)
+						return
+					}
+				}
+			},
+			want: "is not a member of the consensus committee",
+		},
+		{
+			name:  "proposer without beacon key",
+			block: 2,
+			tamper: func(h *snapshots.Header, commit *snapshots.EpochCommit, nodes []snapshotstest.Node) {
+				commit.DKGIndexMap = map[string]int{}
+				commit.DKGParticipantKeys = nil
+			},
+			want: "no random beacon key for node",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			snapshot, nodes := buildSignedSnapshot(t, true)
+			entry := snapshot.SealingSegment.ProtocolStateEntry().EpochEntry
+			header := snapshot.SealingSegment.Blocks[test.block].Header
+			commit := entry.CurrentEpochCommit
+			if test.tamper != nil {
+				test.tamper(&header, &commit, nodes)
+			}
+
+			err := header.VerifyProposerSig(entry.CurrentEpochSetup, commit)
+			if test.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("expected an error containing %q, got %v", test.want, err)
+			}
+		})
+	}
+}
+
+func TestVerifyQC(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(qc *snapshots.QuorumCertificate, commit *snapshots.EpochCommit)
+		want   string
+	}{
+		{
+			name:   "valid",
+			tamper: func(qc *snapshots.QuorumCertificate, commit *snapshots.EpochCommit) {},
+		},
+		{
+			name: "flipped bit in staking signature",
+			tamper: func(qc *snapshots.QuorumCertificate, commit *snapshots.EpochCommit) {
+				data := sigData(t, qc.SigData)
+				data.AggregatedStakingSig[len(data.AggregatedStakingSig)-1] ^= 0x01
+				qc.SigData = encodeSigData(t, data)
+			},
+			want: "aggregated staking signature",
+		},
+		{
+			name: "flipped bit in random beacon signature",
+			tamper: func(qc *snapshots.QuorumCertificate, commit *snapshots.EpochCommit) {
+				data := sigData(t, qc.SigData)
+				data.AggregatedRandomBeaconSig[len(data.AggregatedRandomBeaconSig)-1] ^= 0x01
+				qc.SigData = encodeSigData(t, data)
+			},
+			want: "aggregated random beacon signature",
+		},
+		{
+			name: "flipped bit in reconstructed signature",
+			tamper: func(qc *snapshots.QuorumCertificate, commit *snapshots.EpochCommit) {
+				data := sigData(t, qc.SigData)
+				data.ReconstructedRandomBeaconSig[len(data.ReconstructedRandomBeaconSig)-1] ^= 0x01
+				qc.SigData = encodeSigData(t, data)
+			},
+			want: "reconstructed random beacon signature",
+		},
+		{
+			name: "swapped signature types",
+			tamper: func(qc *snapshots.QuorumCertificate, commit *snapshots.EpochCommit) {
+				// the signers at even positions signed with their beacon keys
+				data := sigData(t, qc.SigData)
+				data.SigType[0] ^= 0xe0
+				qc.SigData = encodeSigData(t, data)
+			},
+			want: "aggregated staking signature",
+		},
+		{
+			name: "padding bit",
+			tamper: func(qc *snapshots.QuorumCertificate, commit *snapshots.EpochCommit) {
+				data := sigData(t, qc.SigData)
+				data.SigType[0] |= 0x01
+				qc.SigData = encodeSigData(t, data)
+			},
+			want: "padding bit 7 is set",
+		},
+		{
+			name: "wrong beacon key",
+			tamper: func(qc *snapshots.QuorumCertificate, commit *snapshots.EpochCommit) {
+				keys := append([]string{}, commit.DKGParticipantKeys...)
+				keys[0], keys[1] = keys[1], keys[0]
+				commit.DKGParticipantKeys = keys
+			},
+			want: "aggregated random beacon signature",
+		},
+		{
+			name: "wrong group key",
+			tamper: func(qc *snapshots.QuorumCertificate, commit *snapshots.EpochCommit) {
+				commit.DKGGroupKey = commit.DKGParticipantKeys[0]
+			},
+			want: "reconstructed random beacon signature",
+		},
+		{
+			name: "missing beacon key",
+			tamper: func(qc *snapshots.QuorumCertificate, commit *snapshots.EpochCommit) {
+				commit.DKGIndexMap = map[string]int{}
+				commit.DKGParticipantKeys = nil
+			},
+			want: "no random beacon key for node",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			snapshot, _ := buildSignedSnapshot(t, true)
+			entry := snapshot.SealingSegment.ProtocolStateEntry().EpochEntry
+			qc := snapshot.QuorumCertificate
+			commit := entry.CurrentEpochCommit
+			test.tamper(&qc, &commit)
+
+			err := qc.Verify(entry.CurrentEpochSetup, commit)
+			if test.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("expected an error containing %q, got %v", test.want, err)
+			}
+		})
+	}
+}
+
+// TestVerifyFixtures verifies the signatures of snapshots written by flow-go nodes. Snapshots
+// placed in testdata, for example a spork's root-protocol-state-snapshot.json, are checked with
+// the same code paths as the generated ones.
+func TestVerifyFixtures(t *testing.T) {
+	for name, snapshot := range loadFixtures(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := snapshots.VerifyQC(snapshot); err != nil {
+				t.Errorf("unexpected QC error: %v", err)
+			}
+			if err := snapshots.VerifySealingSegment(snapshot); err != nil {
+				t.Errorf("unexpected sealing segment error: %v", err)
+			}
+		})
+	}
+}
+
+func signVote(t *testing.T, key crypto.PrivateKey, sigType byte, header snapshots.Header) string {
+	t.Helper()
+
+	msg, err := snapshots.VoteMessage(header.View, header.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := snapshots.ConsensusVoteTag
+	if sigType == snapshots.SigTypeRandomBeacon {
+		tag = snapshots.RandomBeaconTag
+	}
+	sig, err := key.Sign(msg, crypto.NewExpandMsgXOFKMAC128(tag))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(append([]byte{sigType}, sig...))
+}
@@ -70,6 +70,31 @@ func (info *SporkInfo) LatestSpork(network string) (*Spork, error) {
 	return latestSpork, nil
 }
 
+// SporkForHeight returns the spork of the network that contains the block height, which is the
+// spork with the highest root height at or below it.
+func (info *SporkInfo) SporkForHeight(network string, height uint64) (*Spork, error) {
+	sporks, ok := info.Networks[network]
+	if !ok {
+		return nil, fmt.Errorf("network %s not found", network)
+	}
+
+	var found *Spork
+	for _, spork := range sporks.Sporks {
+		if spork.RootHeight > height {
+			continue
+		}
+		if found == nil || spork.RootHeight > found.RootHeight {
+			found = &spork
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("no %s spork found for height %d", network, height)
+	}
+
+	return found, nil
+}
+
 // Print prints the spork info to stdout.
 func (info *SporkInfo) Print() {
 	for networkName, network := range info.Networks {
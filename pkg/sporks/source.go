@@ -0,0 +1,72 @@
+package sporks
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/snapshots"
+)
+
+// IsLocation returns true if the source is a url or an existing file, rather than a spork name.
+func IsLocation(source string) bool {
+	if isURL(source) {
+		return true
+	}
+	_, err := os.Stat(source)
+	return err == nil
+}
+
+// Loader loads artefacts from files, urls or sporks given by name. The spork info is loaded on
+// first use, and shared by later loads.
+type Loader struct {
+	info *SporkInfo
+}
+
+// Spork returns the spork with the given name.
+func (l *Loader) Spork(name string) (*Spork, error) {
+	if l.info == nil {
+		info, err := Load()
+		if err != nil {
+			return nil, fmt.Errorf("error loading sporks: %w", err)
+		}
+		l.info = info
+	}
+
+	spork, err := l.info.Spork(name)
+	if err != nil {
+		return nil, fmt.Errorf("error loading spork: %w", err)
+	}
+
+	return spork, nil
+}
+
+// Snapshot loads a snapshot from a file or url, or a spork's root protocol state snapshot.
+func (l *Loader) Snapshot(source string) (*snapshots.Snapshot, error) {
+	if IsLocation(source) {
+		return snapshots.Load(source)
+	}
+
+	spork, err := l.Spork(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return spork.ProtocolStateSnapshot()
+}
+
+// NodeInfo loads node infos from a file or url, or a spork's node infos. Files are read with the
+// codec, which records the field name their stakes use.
+func (l *Loader) NodeInfo(source string, codec *identities.Codec) (identities.IdentityList, error) {
+	if IsLocation(source) {
+		codec.Format = identities.FormatFromPath(source)
+		return codec.Load(source)
+	}
+
+	spork, err := l.Spork(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return spork.Identities()
+}
@@ -0,0 +1,75 @@
+package sporks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+)
+
+func TestIsLocation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "node-infos.pub.json")
+	if err := os.WriteFile(file, []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for source, want := range map[string]bool{
+		"https://example.com/node-infos.pub.json": true,
+		"http://localhost:8080/snapshot.json":     true,
+		file:                                      true,
+		"mainnet26":                               false,
+		filepath.Join(t.TempDir(), "missing.json"): false,
+	} {
+		if got := IsLocation(source); got != want {
+			t.Errorf("%s: expected %t, got %t", source, want, got)
+		}
+	}
+}
+
+func TestLoaderNodeInfo(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "node-infos.pub.json")
+	data := strings.ReplaceAll(nodeInfoJSON("access-001.example.com:3569"), `"Stake"`, `"Weight"`)
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spork := Spork{
+		Name:           "testnet1",
+		StateArtefacts: StateArtefacts{NodeInfo: strings.ReplaceAll(file, "node-infos", "spork-node-infos")},
+	}
+	if err := os.WriteFile(spork.StateArtefacts.NodeInfo, []byte(nodeInfoJSON("access-002.example.com:3569")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info := newSporkInfo()
+	info.Networks["testnet"] = Sporks{Sporks: map[string]Spork{spork.Name: spork}}
+	loader := &Loader{info: info}
+
+	var codec identities.Codec
+	nodes, err := loader.NodeInfo(file, &codec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].Address != "access-001.example.com:3569" || nodes[0].Stake != 100 {
+		t.Errorf("unexpected nodes from the file: %+v", nodes)
+	}
+	if codec.Format != identities.FormatJSON || codec.StakeField != identities.StakeFieldWeight {
+		t.Errorf("expected the codec to record the file format and stake field, got %+v", codec)
+	}
+
+	nodes, err = loader.NodeInfo("testnet1", &identities.Codec{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].Address != "access-002.example.com:3569" {
+		t.Errorf("unexpected nodes from the spork: %+v", nodes)
+	}
+
+	_, err = loader.NodeInfo("testnet2", &identities.Codec{})
+	if err == nil || !strings.Contains(err.Error(), "spork testnet2 not found") {
+		t.Errorf("expected a missing spork error, got %v", err)
+	}
+}
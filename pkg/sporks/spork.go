@@ -2,8 +2,10 @@ package sporks
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/peterargue/flow-info/pkg/checkpoint"
 	"github.com/peterargue/flow-info/pkg/identities"
 	"github.com/peterargue/flow-info/pkg/snapshots"
 )
@@ -43,6 +45,20 @@ type Node struct {
 	Key     string
 }
 
+// PeerID returns the libp2p peer ID derived from the seed node's network key.
+func (n Node) PeerID() (string, error) {
+	return identities.PeerIDFromNetworkKey(n.Key)
+}
+
+// Multiaddr returns the libp2p multiaddr used to dial the seed node, including its peer ID.
+func (n Node) Multiaddr() (string, error) {
+	peerID, err := n.PeerID()
+	if err != nil {
+		return "", err
+	}
+	return identities.Multiaddr(n.Address, peerID)
+}
+
 // Identities returns the initial identities for the spork.
 func (s *Spork) Identities() (identities.IdentityList, error) {
 	return identities.LoadNodeInfo(s.StateArtefacts.NodeInfo)
@@ -53,6 +69,24 @@ func (s *Spork) ProtocolStateSnapshot() (*snapshots.Snapshot, error) {
 	return snapshots.Load(s.StateArtefacts.RootProtocolStateSnapshot)
 }
 
+// Checkpoint reads the header of the spork's root checkpoint, without downloading the checkpoint.
+func (s *Spork) Checkpoint() (*checkpoint.Checkpoint, error) {
+	if s.StateArtefacts.RootCheckpointFile == "" {
+		return nil, fmt.Errorf("spork %s does not have a root checkpoint", s.Name)
+	}
+	return checkpoint.Load(s.StateArtefacts.RootCheckpointFile)
+}
+
+// VerifyCheckpoint checks that the root hash of a checkpoint matches the spork's root state
+// commitment.
+func (s *Spork) VerifyCheckpoint(c *checkpoint.Checkpoint) error {
+	return c.VerifyRootHash(s.RootStateCommitment)
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://")
+}
+
 // Print prints the spork details.
 func (s *Spork) Print() {
 	fmt.Printf("%s:\n", s.Name)
@@ -0,0 +1,87 @@
+package sporks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/peterargue/flow-info/pkg/cache"
+)
+
+func nodeInfoJSON(address string) string {
+	return `[{"Role":"access","Address":"` + address + `","NodeID":"` + strings.Repeat("01", 32) + `","Stake":100}]`
+}
+
+func TestIdentitiesCached(t *testing.T) {
+	var body atomic.Value
+	body.Store(nodeInfoJSON("access-001.example.com:3569"))
+
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(body.Load().(string)))
+	}))
+	defer ts.Close()
+
+	c := cache.New(t.TempDir())
+	cache.SetDefault(c)
+	defer cache.SetDefault(nil)
+
+	spork := &Spork{
+		Name:           "testnet1",
+		StateArtefacts: StateArtefacts{NodeInfo: ts.URL + "/node-infos.pub.json"},
+	}
+
+	address := func() string {
+		t.Helper()
+		nodes, err := spork.Identities()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(nodes) != 1 {
+			t.Fatalf("expected 1 node, got %d", len(nodes))
+		}
+		return nodes[0].Address
+	}
+
+	if got := address(); got != "access-001.example.com:3569" {
+		t.Fatalf("unexpected address %s", got)
+	}
+
+	// node infos have no checksum in sporks.json, so they are cached like other files
+	body.Store(nodeInfoJSON("access-002.example.com:3569"))
+	if got := address(); got != "access-001.example.com:3569" {
+		t.Fatalf("expected the cached file while it is fresh, got %s", got)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("expected 1 request, got %d", n)
+	}
+
+	c.TTL = 0
+	if got := address(); got != "access-002.example.com:3569" {
+		t.Fatalf("expected the republished file once the cached copy expired, got %s", got)
+	}
+}
+
+func TestSeedNodeMultiaddr(t *testing.T) {
+	// the secp256k1 generator point, whose peer ID was derived with go-libp2p
+	node := Node{
+		Address: "access-001.mainnet26.nodes.onflow.org:3570",
+		Key:     "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+	}
+
+	addr, err := node.Multiaddr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "/dns4/access-001.mainnet26.nodes.onflow.org/tcp/3570/p2p/16Uiu2HAm3cuhhRL2msUuLF62KRSfneFDx94RsuouyW25Ho42cFMq"
+	if addr != want {
+		t.Errorf("expected %s, got %s", want, addr)
+	}
+
+	if _, err := (Node{Address: node.Address, Key: "abcd"}).Multiaddr(); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
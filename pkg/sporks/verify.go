@@ -0,0 +1,132 @@
+package sporks
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/snapshots"
+)
+
+// chainIDs maps network names to their chain IDs.
+var chainIDs = map[string]string{
+	"mainnet": "flow-mainnet",
+	"testnet": "flow-testnet",
+}
+
+// Verify loads the spork's root snapshot and node infos, and checks that they are consistent with
+// the spork's metadata. See VerifyArtefacts.
+func (s *Spork) Verify() error {
+	snapshot, err := s.ProtocolStateSnapshot()
+	if err != nil {
+		return fmt.Errorf("error loading root snapshot: %w", err)
+	}
+
+	nodeInfo, err := s.Identities()
+	if err != nil {
+		return fmt.Errorf("error loading node info: %w", err)
+	}
+
+	return s.VerifyArtefacts(snapshot, nodeInfo)
+}
+
+// VerifyArtefacts checks that the spork's root snapshot and node infos are consistent with the
+// spork's metadata, and returns all problems found. It checks the root height, chain ID, root
+// block parent and root state commitment against the snapshot, and that the node infos match the
+// snapshot's identity table.
+func (s *Spork) VerifyArtefacts(snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) error {
+	var errs []error
+	addError := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	params := snapshot.Params
+	if params.SporkRootBlockHeight != s.RootHeight {
+		addError("snapshot spork root height %d does not match root height %d", params.SporkRootBlockHeight, s.RootHeight)
+	}
+
+	network := networkName(s.Name)
+	if expected, ok := chainIDs[network]; !ok {
+		addError("unknown network for spork %s", s.Name)
+	} else if params.ChainID != expected {
+		addError("snapshot chain ID %s does not match network %s (%s)", params.ChainID, network, expected)
+	}
+
+	root := rootBlock(snapshot)
+	if root == nil {
+		addError("snapshot does not contain the root block at height %d", params.SporkRootBlockHeight)
+	} else {
+		if !equalHex(root.ParentID, s.RootParentID) {
+			addError("root block parent %s does not match root parent ID %s", root.ParentID, s.RootParentID)
+		}
+
+		seal, err := rootSeal(snapshot, root)
+		if err != nil {
+			errs = append(errs, err)
+		} else if !equalHex(seal.FinalState, s.RootStateCommitment) {
+			addError("root seal final state %s does not match root state commitment %s", seal.FinalState, s.RootStateCommitment)
+		}
+	}
+
+	for _, change := range identities.Diff(nodeInfo, snapshot.CurrentEpochSetup().Identities()) {
+		addError("node info does not match snapshot identity table: %s", change)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("spork %s: %w", s.Name, errors.Join(errs...))
+	}
+	return nil
+}
+
+// rootBlock returns the header of the block at the spork root height.
+func rootBlock(snapshot *snapshots.Snapshot) *snapshots.Header {
+	for i, block := range snapshot.SealingSegment.Blocks {
+		if block.Header.Height == snapshot.Params.SporkRootBlockHeight {
+			return &snapshot.SealingSegment.Blocks[i].Header
+		}
+	}
+	return nil
+}
+
+// rootSeal returns the latest seal for the root block, which is stored as the sealing segment's
+// first seal for root snapshots. The seal must be recorded in LatestSeals and seal the root block.
+func rootSeal(snapshot *snapshots.Snapshot, root *snapshots.Header) (*snapshots.Seal, error) {
+	segment := snapshot.SealingSegment
+
+	sealID, ok := segment.LatestSeals[root.ID]
+	if !ok {
+		return nil, fmt.Errorf("snapshot does not record the latest seal for the root block %s", root.ID)
+	}
+
+	seal := findSeal(segment, sealID)
+	if seal == nil {
+		return nil, fmt.Errorf("snapshot does not contain the seal %s for the root block %s", sealID, root.ID)
+	}
+	if !equalHex(seal.BlockID, root.ID) {
+		return nil, fmt.Errorf("root seal %s is for block %s, not the root block %s", sealID, seal.BlockID, root.ID)
+	}
+
+	return seal, nil
+}
+
+// findSeal returns the seal with the ID from the sealing segment's first seal or block payloads.
+func findSeal(segment snapshots.SealingSegment, sealID string) *snapshots.Seal {
+	if segment.FirstSeal.ID == sealID {
+		seal := snapshots.Seal(segment.FirstSeal)
+		return &seal
+	}
+
+	for _, block := range segment.Blocks {
+		for i, seal := range block.Payload.Seals {
+			if seal.ID == sealID {
+				return &block.Payload.Seals[i]
+			}
+		}
+	}
+	return nil
+}
+
+func equalHex(a, b string) bool {
+	return strings.EqualFold(strings.TrimPrefix(a, "0x"), strings.TrimPrefix(b, "0x"))
+}
@@ -0,0 +1,208 @@
+package sporks
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/peterargue/flow-info/pkg/identities"
+	"github.com/peterargue/flow-info/pkg/snapshots"
+	"github.com/peterargue/flow-info/pkg/snapshots/snapshotstest"
+)
+
+// verifyFixture builds a root snapshot, and returns it with its node infos and a spork whose
+// metadata matches it.
+func verifyFixture(t *testing.T) (*Spork, *snapshots.Snapshot, identities.IdentityList) {
+	t.Helper()
+
+	builder := snapshotstest.NewBuilder().
+		WithSeed(11).
+		WithChainID("flow-mainnet").
+		WithRootHeight(1000).
+		WithBlocks(3)
+
+	snapshot, err := builder.Build()
+	if err != nil {
+		t.Fatalf("error building snapshot: %v", err)
+	}
+
+	var nodeInfo identities.IdentityList
+	for _, node := range builder.Nodes() {
+		nodeInfo = append(nodeInfo, node.NodeInfo)
+	}
+
+	root := snapshot.SealingSegment.Blocks[0].Header
+	spork := &Spork{
+		Name:                "mainnet26",
+		RootHeight:          root.Height,
+		RootParentID:        root.ParentID,
+		RootStateCommitment: snapshot.SealingSegment.FirstSeal.FinalState,
+	}
+
+	return spork, snapshot, nodeInfo
+}
+
+func TestVerifyArtefacts(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList
+		want   []string
+	}{
+		{
+			name: "consistent",
+		},
+		{
+			name: "prefixed and upper case hex",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				s.RootStateCommitment = "0x" + strings.ToUpper(s.RootStateCommitment)
+				return nodeInfo
+			},
+		},
+		{
+			name: "root height",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				s.RootHeight++
+				return nodeInfo
+			},
+			want: []string{"snapshot spork root height 1000 does not match root height 1001"},
+		},
+		{
+			name: "chain ID",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				s.Name = "testnet50"
+				return nodeInfo
+			},
+			want: []string{"snapshot chain ID flow-mainnet does not match network testnet (flow-testnet)"},
+		},
+		{
+			name: "root parent",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				s.RootParentID = strings.Repeat("ab", 32)
+				return nodeInfo
+			},
+			want: []string{"does not match root parent ID " + strings.Repeat("ab", 32)},
+		},
+		{
+			name: "root state commitment",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				s.RootStateCommitment = strings.Repeat("cd", 32)
+				return nodeInfo
+			},
+			want: []string{"does not match root state commitment " + strings.Repeat("cd", 32)},
+		},
+		{
+			name: "missing root block",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				snapshot.SealingSegment.Blocks = snapshot.SealingSegment.Blocks[1:]
+				return nodeInfo
+			},
+			want: []string{"snapshot does not contain the root block at height 1000"},
+		},
+		{
+			name: "root seal not recorded",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				delete(snapshot.SealingSegment.LatestSeals, snapshot.SealingSegment.Blocks[0].Header.ID)
+				return nodeInfo
+			},
+			want: []string{"snapshot does not record the latest seal for the root block"},
+		},
+		{
+			name: "root seal missing",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				snapshot.SealingSegment.LatestSeals[snapshot.SealingSegment.Blocks[0].Header.ID] = strings.Repeat("ef", 32)
+				return nodeInfo
+			},
+			want: []string{"snapshot does not contain the seal " + strings.Repeat("ef", 32)},
+		},
+		{
+			name: "seal for another block",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				snapshot.SealingSegment.FirstSeal.BlockID = snapshot.SealingSegment.Blocks[1].Header.ID
+				return nodeInfo
+			},
+			want: []string{"is for block", "not the root block"},
+		},
+		{
+			name: "stake",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				nodeInfo[0].Stake++
+				return nodeInfo
+			},
+			want: []string{"node info does not match snapshot identity table"},
+		},
+		{
+			name: "zero stake",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				nodeInfo[0].Stake = 0
+				return nodeInfo
+			},
+			want: []string{"node info does not match snapshot identity table"},
+		},
+		{
+			name: "missing node",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				return nodeInfo[1:]
+			},
+			want: []string{"node info does not match snapshot identity table"},
+		},
+		{
+			name: "weight field",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				// newer node infos files record the stake as Weight
+				data, err := json.Marshal(nodeInfo)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data = []byte(strings.ReplaceAll(string(data), `"Stake":`, `"Weight":`))
+
+				decoded, err := identities.ReadNodeInfo(bytes.NewReader(data), identities.FormatJSON)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return decoded
+			},
+		},
+		{
+			name: "snapshot weight field",
+			tamper: func(s *Spork, snapshot *snapshots.Snapshot, nodeInfo identities.IdentityList) identities.IdentityList {
+				// older snapshots record the stake as Weight instead of InitialWeight
+				participants := snapshot.CurrentEpochSetup().Participants
+				for i := range participants {
+					participants[i].Weight = participants[i].InitialWeight
+					participants[i].InitialWeight = 0
+				}
+				if snapshot.CurrentEpochSetup().Participants[0].InitialWeight != 0 {
+					t.Fatal("expected the snapshot participants to be modified")
+				}
+				return nodeInfo
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spork, snapshot, nodeInfo := verifyFixture(t)
+			if test.tamper != nil {
+				nodeInfo = test.tamper(spork, snapshot, nodeInfo)
+			}
+
+			err := spork.VerifyArtefacts(snapshot, nodeInfo)
+			if len(test.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected an error containing %q", test.want)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}